@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/daemon"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+func runAttach(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("attach", "attach", "Opens the console of a running launcher, replaying recent output. Lines you type are sent to the server; press Ctrl+C to detach without stopping it.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	logger.Info("Attached to launcher console, press Ctrl+C to detach")
+	if err := daemon.Attach(ctx, cfg.SocketFile, os.Stdin, os.Stdout); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		logger.Info("Detached, the server keeps running")
+	} else {
+		logger.Info("Launcher exited")
+	}
+	return nil
+}
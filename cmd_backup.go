@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/backup"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/server"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+func runBackup(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("backup", "backup [flags] | backup verify [backup-name] | backup prune [-dry-run]", "Archives the configured world folders into the backup directory, verifies the new backup and prunes old backups. 'backup verify' re-reads existing backups and checks them against their manifests. 'backup prune' deletes the backups the retention policy no longer keeps.")
+	worlds := fs.String("worlds", "", "Comma-separated world folders (default: backup_worlds)")
+	dryRun := fs.Bool("dry-run", false, "prune: only list the backups that would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	action := ""
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+		// Flags may also follow the action.
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+	switch action {
+	case "":
+	case "verify":
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return fmt.Errorf("backup verify takes at most one backup name")
+		}
+		return verifyBackups(cfg.BackupDir, fs.Arg(0))
+	case "prune":
+		return pruneBackups(cfg, *dryRun)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown backup action: %s", action)
+	}
+
+	jar, _ := utils.FindJarFile(cfg.ServerType)
+	backup.SetVersions(update.GetCurrentVersion(), jar)
+
+	list := cfg.BackupWorlds
+	if *worlds != "" {
+		list = strings.Split(*worlds, ",")
+	}
+
+	retention := backupRetention(cfg)
+	if pid, running := pidfile.Running(cfg.PIDFile); running {
+		client, err := dialServerRCON(ctx)
+		if err != nil {
+			logger.Warn("Launcher is running (pid %d) but RCON is unavailable (%v); the backup may contain partially saved chunks", pid, err)
+		} else {
+			defer client.Close()
+			logger.Info("Launcher is running (pid %d), pausing world saving over RCON", pid)
+			return backup.LiveBackup(ctx, backup.RCONServer(client), list, cfg.BackupDir, retention)
+		}
+	}
+	return backup.PerformBackup(list, cfg.BackupDir, retention)
+}
+
+// backupRetention returns the retention policy configured by backup_count,
+// backup_retention and backup_max_size.
+func backupRetention(cfg *config.Config) backup.Retention {
+	return backup.Retention{
+		Count:   cfg.BackupCount,
+		GFS:     cfg.BackupRetention == config.BackupRetentionGFS,
+		MaxSize: int64(cfg.BackupMaxSize) * 1024 * 1024,
+	}
+}
+
+// pruneBackups deletes, or with dryRun lists, the backups the retention
+// policy no longer keeps.
+func pruneBackups(cfg *config.Config, dryRun bool) error {
+	if !dryRun {
+		// A backup in progress may be writing chunks no snapshot refers to yet.
+		if pid, running := pidfile.Running(cfg.PIDFile); running {
+			return fmt.Errorf("cannot prune while the launcher is running (pid %d); it prunes after every backup, or use -dry-run", pid)
+		}
+	}
+
+	expired, err := backup.Prune(cfg.BackupDir, backupRetention(cfg), dryRun)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(expired) == 0:
+		fmt.Println("No backups to delete")
+	case dryRun:
+		fmt.Printf("%d backups would be deleted:\n", len(expired))
+		for _, a := range expired {
+			fmt.Printf("  %-30s %9s %s\n", a.Name, formatMB(a.Size), a.Created.Format("2006-01-02 15:04"))
+		}
+	default:
+		fmt.Printf("Deleted %d backups\n", len(expired))
+	}
+	return nil
+}
+
+// verifyBackups checks the named backup, or every backup when name is
+// empty, and fails if any of them is damaged.
+func verifyBackups(backupDir, name string) error {
+	var archives []backup.Archive
+	if name != "" {
+		a, err := backup.Find(backupDir, name)
+		if err != nil {
+			return err
+		}
+		archives = append(archives, a)
+	} else {
+		var err error
+		if archives, err = backup.List(backupDir); err != nil {
+			return err
+		}
+		if len(archives) == 0 {
+			fmt.Printf("No backups in %s\n", backupDir)
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, a := range archives {
+		res, err := backup.Verify(a.Path)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("FAILED %s: %v\n", a.Name, err)
+		case !res.Manifest:
+			fmt.Printf("OK     %s: %d files, %s (no manifest, checked CRCs only)\n", a.Name, res.Files, formatMB(res.Size))
+		default:
+			fmt.Printf("OK     %s: %d files, %s\n", a.Name, res.Files, formatMB(res.Size))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d backups failed verification", failed, len(archives))
+	}
+	return nil
+}
+
+// scheduleBackups backs up the worlds of the running server every
+// backup_interval minutes until ctx is done.
+func scheduleBackups(ctx context.Context, cfg *config.Config, con *console.Console, readiness *server.Readiness) {
+	ticker := time.NewTicker(time.Duration(cfg.BackupInterval) * time.Minute)
+	defer ticker.Stop()
+
+	srv := backup.ConsoleServer(con)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !readiness.IsReady() || !con.Running() {
+			logger.Info("Server is not running, skipping scheduled backup")
+			continue
+		}
+		logger.Info("Starting scheduled backup")
+		if err := backup.LiveBackup(ctx, srv, cfg.BackupWorlds, cfg.BackupDir, backupRetention(cfg)); err != nil && ctx.Err() == nil {
+			logger.Error("Scheduled backup failed: %v", err)
+		}
+	}
+}
+
+func runRestore(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("restore", "restore [flags] [backup-name]", "Replaces the world folders with the contents of a backup archive. The current folders are kept as a safety copy. Without a backup name, lists the available backups.")
+	worlds := fs.String("world", "", "Comma-separated worlds to restore from the backup (default: all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("restore takes at most one backup name")
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return printBackups(cfg.BackupDir)
+	}
+
+	if pid, running := pidfile.Running(cfg.PIDFile); running {
+		return fmt.Errorf("cannot restore while the launcher is running (pid %d); stop it first", pid)
+	}
+
+	archive := fs.Arg(0)
+	if _, err := os.Stat(archive); err != nil {
+		found, err := backup.Find(cfg.BackupDir, archive)
+		if err != nil {
+			return err
+		}
+		archive = found.Path
+	}
+
+	var only []string
+	if *worlds != "" {
+		only = strings.Split(*worlds, ",")
+		logger.Info("Restoring %s from %s...", strings.Join(only, ", "), archive)
+	} else {
+		logger.Info("Restoring %s...", archive)
+	}
+	safety, err := backup.RestoreWorlds(archive, ".", only)
+	if err != nil {
+		return err
+	}
+	for _, s := range safety {
+		logger.Info("Previous world kept at %s", s)
+	}
+	logger.Info("Restore complete")
+	return nil
+}
+
+func printBackups(backupDir string) error {
+	archives, err := backup.List(backupDir)
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		fmt.Printf("No backups in %s\n", backupDir)
+		return nil
+	}
+	fmt.Printf("%-30s %9s %-16s %s\n", "BACKUP", "SIZE", "CREATED", "WORLDS")
+	for _, a := range archives {
+		worlds, err := backup.Worlds(a.Path)
+		list := strings.Join(worlds, ", ")
+		if err != nil {
+			list = "unreadable: " + err.Error()
+		}
+		fmt.Printf("%-30s %9s %-16s %s\n", a.Name, formatMB(a.Size), a.Created.Format("2006-01-02 15:04"), list)
+	}
+	return nil
+}
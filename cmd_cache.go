@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/cache"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+const defaultCachePruneDays = 30
+
+func runCache(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("cache", "cache [list|prune] [flags]",
+		"list shows the server JARs in the download cache shared by every server on this host,\n"+
+			"and prune removes the ones no server has installed recently.")
+	days := fs.Int("days", defaultCachePruneDays, "prune: remove JARs not used for this many days")
+	all := fs.Bool("all", false, "prune: remove every cached JAR")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	action := "list"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+		// Flags may also follow the action.
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	c, err := openCache(cfg)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("the download cache is disabled (cache_dir: %s)", cache.Disabled)
+	}
+
+	switch action {
+	case "list":
+		entries, err := c.List()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cache: %s\n", c.Dir())
+		if len(entries) == 0 {
+			fmt.Println("No cached JARs")
+			return nil
+		}
+		var total int64
+		fmt.Printf("%-12s %9s %-16s %s\n", "SHA-256", "SIZE", "LAST USED", "JAR")
+		for _, e := range entries {
+			total += e.Size
+			fmt.Printf("%-12s %9s %-16s %s\n", e.SHA256[:12], formatMB(e.Size), e.LastUsed.Format("2006-01-02 15:04"), e.Name)
+		}
+		fmt.Printf("%d JARs, %s\n", len(entries), formatMB(total))
+		return nil
+	case "prune":
+		maxAge := time.Duration(*days) * 24 * time.Hour
+		if *all {
+			maxAge = 0
+		}
+		removed, err := c.Prune(maxAge)
+		for _, e := range removed {
+			logger.Info("Removed %s (%s)", e.Name, e.SHA256[:12])
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("Removed %d cached JARs", len(removed))
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown cache action: %s", action)
+	}
+}
+
+// openCache returns the download cache configured by cache_dir, or nil when
+// it is turned off.
+func openCache(cfg *config.Config) (*cache.Cache, error) {
+	switch cfg.CacheDir {
+	case cache.Disabled:
+		return nil, nil
+	case "":
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+		return cache.New(dir), nil
+	default:
+		return cache.New(cfg.CacheDir), nil
+	}
+}
+
+func formatMB(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+}
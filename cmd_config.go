@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func runConfig(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("config", "config [show|validate|path]",
+		"show prints the effective configuration after defaults and environment overrides,\n"+
+			"validate checks the config file, and path prints its location.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	action := "show"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+
+	switch action {
+	case "show":
+		shown := *cfg
+		if shown.GitHubToken != "" {
+			shown.GitHubToken = "********"
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(&shown); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		return enc.Close()
+	case "validate":
+		// Load already validated the file before any command runs.
+		fmt.Printf("%s is valid\n", *configFile)
+		return nil
+	case "path":
+		path, err := filepath.Abs(*configFile)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown config action: %s", action)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/ping"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/server"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+type doctorReport struct {
+	failures int
+}
+
+func (r *doctorReport) ok(format string, args ...interface{}) {
+	fmt.Printf("[ OK ] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) warn(format string, args ...interface{}) {
+	fmt.Printf("[WARN] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) fail(format string, args ...interface{}) {
+	r.failures++
+	fmt.Printf("[FAIL] %s\n", fmt.Sprintf(format, args...))
+}
+
+func runDoctor(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("doctor", "doctor", "Checks the environment the server needs and reports problems.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	r := &doctorReport{}
+	r.ok("Config %s is valid", *configFile)
+
+	javaPath := cfg.JavaPath
+	if javaPath == "" {
+		javaPath = "java"
+	}
+	ver, javaVersion, err := server.CheckJava(javaPath)
+	if err != nil {
+		r.fail("Java: %v", err)
+	} else if cfg.UseZGC && javaVersion < 17 {
+		r.warn("Java %s: generational ZGC requires Java 17+", ver)
+	} else {
+		r.ok("Java %s", ver)
+	}
+
+	if total, avail, err := server.GetSystemRAM(); err != nil {
+		r.warn("Could not read system memory: %v", err)
+	} else if maxRAM := server.CalculateSmartRAM(cfg.MaxRAM, cfg.AutoRAMPercentage, cfg.MinRAM, avail); cfg.MinRAM > avail {
+		r.warn("min_ram (%dG) exceeds available memory (%dG of %dG)", cfg.MinRAM, avail, total)
+	} else {
+		r.ok("Memory: %dG available of %dG, server would use %dG - %dG", avail, total, cfg.MinRAM, maxRAM)
+	}
+
+	if !download.IsProxy(cfg.ServerType) {
+		if data, err := os.ReadFile("eula.txt"); err != nil || !strings.Contains(string(data), "eula=true") {
+			r.warn("EULA not accepted yet (it is accepted automatically on start)")
+		} else {
+			r.ok("EULA accepted")
+		}
+	}
+
+	checkServerJar(ctx, cfg, r, javaVersion)
+	checkServerPort(cfg, r)
+
+	if cfg.AutoBackup {
+		if err := checkWritable(cfg.BackupDir); err != nil {
+			r.fail("Backup directory %s: %v", cfg.BackupDir, err)
+		} else {
+			r.ok("Backup directory %s is writable", cfg.BackupDir)
+		}
+	}
+
+	if r.failures > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", r.failures)
+	}
+	return nil
+}
+
+func checkServerJar(ctx context.Context, cfg *config.Config, r *doctorReport, javaVersion int) {
+	jarFile, err := utils.FindJarFile(cfg.ServerType)
+	if err != nil {
+		r.fail("Server JAR: %v", err)
+		return
+	}
+	if jarFile == "" {
+		r.warn("No server JAR found (it is downloaded on start)")
+		return
+	}
+
+	if err := utils.ValidateJarFile(jarFile); err != nil {
+		r.fail("Server JAR %s: %v", jarFile, err)
+		return
+	}
+	expected, err := utils.LoadChecksumFile(jarFile + ".sha256")
+	switch {
+	case err != nil:
+		r.warn("Server JAR %s: %v", jarFile, err)
+	case expected == "":
+		r.warn("Server JAR %s has no checksum file", jarFile)
+	default:
+		if err := utils.ValidateChecksum(jarFile, expected); err != nil {
+			r.fail("Server JAR %s: %v", jarFile, err)
+			return
+		}
+		r.ok("Server JAR %s (checksum OK)", jarFile)
+	}
+
+	var ver *download.Version
+	if utils.Offline() {
+		r.ok("Offline mode: skipped the server update check")
+		version, _, ok := download.ParseJarName(filepath.Base(jarFile))
+		if !ok {
+			return
+		}
+		cached, saved, err := download.CachedVersion(version)
+		if err != nil {
+			r.warn("Could not check the Java requirement offline: %v", err)
+			return
+		}
+		r.ok("Using %s %s details saved on %s", cfg.ServerType, cached.ID, saved.Format("2006-01-02 15:04"))
+		ver = cached
+	} else {
+		upd, err := download.CheckUpdate(ctx, jarFile)
+		if err != nil {
+			r.warn("Could not check for server updates: %v", err)
+			return
+		}
+		switch {
+		case upd.Available:
+			r.warn("Server JAR update available (%s)", upd.Latest.Download.Name)
+		case upd.SkipReason != "":
+			r.ok("Server JAR is kept: %s", upd.SkipReason)
+		default:
+			r.ok("Server JAR is up to date")
+		}
+		ver = upd.Version
+	}
+	if ver.Support != "" && ver.Support != download.SupportSupported {
+		r.warn("%s %s is %s upstream", cfg.ServerType, ver.ID, strings.ToLower(ver.Support))
+	}
+	if ver.MinJava > 0 && javaVersion > 0 && javaVersion < ver.MinJava {
+		r.fail("Minecraft %s requires Java %d or newer, found Java %d", ver.ID, ver.MinJava, javaVersion)
+	}
+}
+
+func checkServerPort(cfg *config.Config, r *doctorReport) {
+	props, err := utils.LoadServerProperties(utils.ServerPropertiesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		r.fail("server.properties: %v", err)
+		return
+	}
+	addr, err := ping.AddressFromProperties(props)
+	if err != nil {
+		r.fail("server.properties: %v", err)
+		return
+	}
+
+	if _, running := pidfile.Running(cfg.PIDFile); running {
+		r.ok("Launcher is running, skipping port check for %s", addr)
+		return
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		r.fail("Port %s is not available: %v", addr, err)
+		return
+	}
+	l.Close()
+	r.ok("Port %s is available", addr)
+}
+
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	testFile := filepath.Join(dir, ".write-test")
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		return err
+	}
+	return os.Remove(testFile)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/rcon"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+func runRCON(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("rcon", "rcon [flags] [command...]", "Runs a single command, or starts an interactive shell when no command is given.")
+	addr := fs.String("address", "", "RCON address (default: from server.properties)")
+	password := fs.String("password", "", "RCON password (default: from server.properties)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	if *addr == "" || *password == "" {
+		props, err := utils.LoadServerProperties(utils.ServerPropertiesFile)
+		if err != nil {
+			return err
+		}
+		propAddr, propPassword, err := rcon.AddressFromProperties(props)
+		if err != nil {
+			return err
+		}
+		if *addr == "" {
+			*addr = propAddr
+		}
+		if *password == "" {
+			*password = propPassword
+		}
+	}
+
+	client, err := rcon.Dial(ctx, *addr, *password)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if fs.NArg() > 0 {
+		resp, err := client.Execute(ctx, strings.Join(fs.Args(), " "))
+		if err != nil {
+			return err
+		}
+		printRCONResponse(resp)
+		return nil
+	}
+
+	return rconShell(ctx, client, *addr)
+}
+
+func rconShell(ctx context.Context, client *rcon.Client, addr string) error {
+	fmt.Printf("Connected to %s. Type 'exit' to quit.\n", addr)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("rcon> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+
+		resp, err := client.Execute(ctx, line)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		printRCONResponse(resp)
+	}
+}
+
+func printRCONResponse(resp string) {
+	resp = utils.StripFormatting(resp)
+	if resp == "" {
+		return
+	}
+	fmt.Println(strings.TrimRight(resp, "\n"))
+}
+
+// dialServerRCON connects to the RCON address configured in server.properties.
+func dialServerRCON(ctx context.Context) (*rcon.Client, error) {
+	props, err := utils.LoadServerProperties(utils.ServerPropertiesFile)
+	if err != nil {
+		return nil, err
+	}
+	addr, password, err := rcon.AddressFromProperties(props)
+	if err != nil {
+		return nil, err
+	}
+	return rcon.Dial(ctx, addr, password)
+}
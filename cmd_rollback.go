@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/history"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+func runRollback(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("rollback", "rollback [flags] [build|jar]",
+		"Switches back to a server JAR replaced by an earlier update: the most recent one, or the given build or JAR name.\n"+
+			"Automatic updates are held until the next 'paper-launcher update'.")
+	list := fs.Bool("list", false, "List the kept server JARs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("rollback takes at most one build or JAR name")
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	entries, err := history.List(cfg.VersionsDir)
+	if err != nil {
+		return err
+	}
+	active, err := utils.FindJarFile(cfg.ServerType)
+	if err != nil {
+		return err
+	}
+
+	if *list {
+		printHistory(entries, active, history.Held(cfg.VersionsDir))
+		return nil
+	}
+
+	if pid, running := pidfile.Running(cfg.PIDFile); running {
+		return fmt.Errorf("cannot roll back while the launcher is running (pid %d); stop it first", pid)
+	}
+
+	target, err := findHistoryEntry(entries, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := history.Switch(cfg.VersionsDir, target.Name, active, "."); err != nil {
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+	if err := history.Hold(cfg.VersionsDir, target.Name); err != nil {
+		logger.Warn("Failed to hold updates: %v", err)
+	}
+	pruneHistory(cfg)
+
+	if active != "" {
+		logger.Info("Replaced %s, kept in %s", active, cfg.VersionsDir)
+	}
+	logger.Info("Rolled back to %s", target.Name)
+	logger.Info("Automatic updates are held until you run 'paper-launcher update'")
+	return nil
+}
+
+// findHistoryEntry picks the entry named by arg, which may be a JAR name or
+// a build number. An empty arg selects the most recently replaced JAR.
+func findHistoryEntry(entries []history.Entry, arg string) (*history.Entry, error) {
+	for i, e := range entries {
+		_, build, ok := download.ParseJarName(e.Name)
+		if !ok {
+			continue
+		}
+		if arg == "" || e.Name == arg || e.Name == arg+".jar" || build == arg {
+			return &entries[i], nil
+		}
+	}
+	if arg == "" {
+		return nil, fmt.Errorf("no previous server JAR to roll back to")
+	}
+	return nil, fmt.Errorf("%w: %s (see 'paper-launcher rollback -list')", history.ErrNotFound, arg)
+}
+
+func printHistory(entries []history.Entry, active, held string) {
+	if active != "" {
+		note := ""
+		if held == filepath.Base(active) {
+			note = " (updates held)"
+		}
+		fmt.Printf("Active: %s%s\n", active, note)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No previous server JARs are kept")
+		return
+	}
+	fmt.Printf("%-8s %-12s %-16s %s\n", "BUILD", "VERSION", "REPLACED", "JAR")
+	for _, e := range entries {
+		version, build, ok := download.ParseJarName(e.Name)
+		if !ok {
+			version, build = "?", "?"
+		} else if build == "" {
+			build = "-"
+		}
+		fmt.Printf("%-8s %-12s %-16s %s\n", build, version, e.Replaced.Format("2006-01-02 15:04"), e.Name)
+	}
+}
+
+// pruneHistory drops the oldest kept JARs beyond versions_keep.
+func pruneHistory(cfg *config.Config) {
+	removed, err := history.Prune(cfg.VersionsDir, cfg.VersionsKeep)
+	if err != nil {
+		logger.Warn("Failed to prune old server JARs: %v", err)
+	}
+	if len(removed) > 0 {
+		logger.Debug("Removed old server JARs: %s", strings.Join(removed, ", "))
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/backup"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/daemon"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/server"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
+)
+
+// serverProfile holds what differs between server types once the JAR is in place.
+type serverProfile struct {
+	// stopCommand replaces the default "stop" for servers that do not know it.
+	stopCommand  string
+	readyPattern *regexp.Regexp
+}
+
+// BungeeCord-based proxies never print "Done (...)!".
+var waterfallReadyRegex = regexp.MustCompile(`Listening on /`)
+
+func profileFor(serverType string) serverProfile {
+	switch serverType {
+	case download.TypeWaterfall:
+		return serverProfile{stopCommand: "end", readyPattern: waterfallReadyRegex}
+	case download.TypeVelocity:
+		return serverProfile{stopCommand: "end"}
+	default:
+		return serverProfile{}
+	}
+}
+
+type javaCheckResult struct {
+	version    string
+	versionNum int
+	err        error
+}
+
+func runStart(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("start", "start [-detach]", "Checks for updates, prepares the server JAR, backs up the worlds if enabled and runs the server.")
+	detach := fs.Bool("detach", false, "Run the launcher in the background; use 'attach' to open its console")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *detach {
+		return detachLauncher(ctx, cfg)
+	}
+	return startServer(ctx, cfg)
+}
+
+func startServer(ctx context.Context, cfg *config.Config) error {
+	logger.Info("Launcher started (version: %s)", update.GetCurrentVersion())
+
+	update.SetGitHubToken(cfg.GitHubToken)
+	checkLauncherUpdate(ctx, cfg.AutoUpdateLauncher)
+
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	if err := pidfile.Write(cfg.PIDFile); err != nil {
+		return err
+	}
+	defer func() {
+		if err := pidfile.Remove(cfg.PIDFile); err != nil {
+			logger.Warn("%v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	javaPath := cfg.JavaPath
+	if javaPath == "" {
+		javaPath = "java"
+	}
+	javaChan := make(chan javaCheckResult, 1)
+	go func() {
+		ver, num, err := server.CheckJava(javaPath)
+		javaChan <- javaCheckResult{ver, num, err}
+	}()
+
+	var avail int
+	if total, a, err := server.GetSystemRAM(); err != nil {
+		logger.Warn("Failed to get system RAM info: %v", err)
+	} else {
+		avail = a
+		logger.Info("System RAM: %d GB total, %d GB available", total, avail)
+	}
+
+	select {
+	case javaRes := <-javaChan:
+		if javaRes.err != nil {
+			return javaRes.err
+		}
+		logger.Info("Java version: %s", javaRes.version)
+
+		jarFile, err := prepareServerJar(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		backup.SetVersions(update.GetCurrentVersion(), jarFile)
+
+		if cfg.AutoBackup {
+			if err := backup.PerformBackup(cfg.BackupWorlds, cfg.BackupDir, backupRetention(cfg)); err != nil {
+				return fmt.Errorf("backup failed: %w", err)
+			}
+		}
+
+		// The JAR and EULA prompts above read stdin themselves, so the
+		// console only takes it over once they are done.
+		con := console.New(os.Stdout)
+		go con.ForwardInput(os.Stdin)
+
+		if ctl, err := daemon.Listen(cfg.SocketFile, con, cancel); err != nil {
+			logger.Warn("Console socket unavailable, attach and stop will not work: %v", err)
+		} else {
+			defer ctl.Close()
+		}
+
+		maxRAM := server.CalculateSmartRAM(cfg.MaxRAM, cfg.AutoRAMPercentage, cfg.MinRAM, avail)
+		if cfg.MaxRAM == 0 {
+			logger.Info("Starting server with %dG - %dG RAM (auto: %d%% of available)", cfg.MinRAM, maxRAM, cfg.AutoRAMPercentage)
+		} else {
+			logger.Info("Starting server with %dG - %dG RAM", cfg.MinRAM, maxRAM)
+		}
+
+		policy := server.RestartPolicy{
+			Enabled:     cfg.AutoRestart,
+			Delay:       time.Duration(cfg.RestartDelay) * time.Second,
+			MaxDelay:    time.Duration(cfg.RestartMaxDelay) * time.Second,
+			CrashLimit:  cfg.CrashLimit,
+			CrashWindow: time.Duration(cfg.CrashWindow) * time.Minute,
+		}
+		readiness := server.NewReadiness()
+		if cfg.BackupInterval > 0 && !download.IsProxy(cfg.ServerType) {
+			go scheduleBackups(ctx, cfg, con, readiness)
+		}
+		profile := profileFor(cfg.ServerType)
+		stopCommand := cfg.StopCommand
+		if profile.stopCommand != "" && stopCommand == "stop" {
+			stopCommand = profile.stopCommand
+		}
+
+		return server.Supervise(ctx, policy, func(ctx context.Context) error {
+			return server.RunServer(ctx, server.Options{
+				JarFile:     jarFile,
+				MinRAM:      cfg.MinRAM,
+				MaxRAM:      maxRAM,
+				UseZGC:      cfg.UseZGC,
+				JavaPath:    javaPath,
+				JavaVersion: javaRes.versionNum,
+				ServerArgs:  cfg.ServerArgs,
+				Console:     con,
+				StopCommand: stopCommand,
+				StopTimeout: time.Duration(cfg.StopTimeout) * time.Second,
+
+				Readiness:      readiness,
+				StartupTimeout: time.Duration(cfg.StartupTimeout) * time.Second,
+				ReadyPattern:   profile.readyPattern,
+			})
+		})
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// daemonEnv marks a launcher started by 'start -detach'. Its output already
+// goes to the log file, so it must not open the log file a second time.
+const daemonEnv = "PAPER_LAUNCHER_DAEMON"
+
+const detachStartTimeout = 30 * time.Second
+
+// detachLauncher re-runs the launcher in the background with its output
+// appended to the log file, and returns once its console socket is up.
+func detachLauncher(ctx context.Context, cfg *config.Config) error {
+	if pid, running := pidfile.Running(inWorkDir(cfg, cfg.PIDFile)); running {
+		return fmt.Errorf("launcher is already running (pid %d)", pid)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate launcher executable: %w", err)
+	}
+
+	logPath := inWorkDir(cfg, cfg.LogFile)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, withoutDetachFlag(os.Args[1:])...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	setDetachAttr(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start launcher in background: %w", err)
+	}
+	pid := cmd.Process.Pid
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	socketPath := inWorkDir(cfg, cfg.SocketFile)
+	deadline := time.After(detachStartTimeout)
+	for {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			logger.Info("Launcher running in the background (pid %d), logging to %s", pid, logPath)
+			logger.Info("Use 'paper-launcher attach' to open the console and 'paper-launcher stop' to stop it")
+			return nil
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("background launcher exited during startup (see %s): %v", logPath, err)
+		case <-deadline:
+			logger.Warn("Background launcher (pid %d) is still starting, see %s", pid, logPath)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(stopPollInterval):
+		}
+	}
+}
+
+// withoutDetachFlag returns the command line for the background launcher.
+func withoutDetachFlag(args []string) []string {
+	out := make([]string, 0, len(args))
+	for _, a := range args {
+		switch a {
+		case "-detach", "--detach", "-detach=true", "--detach=true":
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// inWorkDir resolves a path from the config before enterWorkDir has run.
+func inWorkDir(cfg *config.Config, name string) string {
+	if cfg.WorkDir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(cfg.WorkDir, name)
+}
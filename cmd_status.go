@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/ping"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/query"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+type statusReport struct {
+	Process struct {
+		Running bool `json:"running"`
+		PID     int  `json:"pid,omitempty"`
+	} `json:"process"`
+	Address   string   `json:"address"`
+	Online    bool     `json:"online"`
+	Error     string   `json:"error,omitempty"`
+	LatencyMS int64    `json:"latency_ms,omitempty"`
+	MOTD      string   `json:"motd,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Protocol  int      `json:"protocol,omitempty"`
+	Players   *players `json:"players,omitempty"`
+
+	Query      *query.FullStat `json:"query,omitempty"`
+	QueryError string          `json:"query_error,omitempty"`
+}
+
+type players struct {
+	Online int      `json:"online"`
+	Max    int      `json:"max"`
+	Names  []string `json:"names"`
+}
+
+func runStatus(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("status", "status [flags]", "Shows whether the launcher is running and queries the server with Server List Ping.")
+	addr := fs.String("address", "", "Server address (default: from server.properties)")
+	asJSON := fs.Bool("json", false, "Print status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	var report statusReport
+	report.Process.PID, report.Process.Running = pidfile.Running(cfg.PIDFile)
+
+	props, err := utils.LoadServerProperties(utils.ServerPropertiesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	report.Address = *addr
+	if report.Address == "" {
+		if report.Address, err = ping.AddressFromProperties(props); err != nil {
+			return err
+		}
+	}
+
+	if status, err := ping.Ping(ctx, report.Address); err != nil {
+		report.Error = err.Error()
+	} else {
+		report.Online = true
+		report.LatencyMS = status.Latency.Milliseconds()
+		report.MOTD = status.MOTD()
+		report.Version = status.Version.Name
+		report.Protocol = status.Version.Protocol
+		report.Players = &players{Online: status.Players.Online, Max: status.Players.Max, Names: []string{}}
+		for _, p := range status.Players.Sample {
+			report.Players.Names = append(report.Players.Names, p.Name)
+		}
+	}
+
+	// Server List Ping only returns a sample of players; the query
+	// protocol lists all of them when the server has it enabled.
+	if queryAddr, err := query.AddressFromProperties(props); err == nil && *addr == "" {
+		if stat, err := query.Query(ctx, queryAddr); err != nil {
+			report.QueryError = err.Error()
+		} else {
+			report.Query = stat
+			if report.Players != nil {
+				report.Players.Names = stat.Players
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printStatus(report)
+	return nil
+}
+
+func printStatus(r statusReport) {
+	if r.Process.Running {
+		fmt.Printf("Launcher: running (pid %d)\n", r.Process.PID)
+	} else {
+		fmt.Println("Launcher: not running")
+	}
+
+	if !r.Online {
+		fmt.Printf("Server:   offline (%s)\n", r.Error)
+		return
+	}
+	fmt.Printf("Server:   online at %s (%d ms)\n", r.Address, r.LatencyMS)
+	fmt.Printf("MOTD:     %s\n", strings.ReplaceAll(r.MOTD, "\n", " / "))
+	fmt.Printf("Version:  %s (protocol %d)\n", r.Version, r.Protocol)
+	fmt.Printf("Players:  %d/%d", r.Players.Online, r.Players.Max)
+	if len(r.Players.Names) > 0 {
+		fmt.Printf(" - %s", strings.Join(r.Players.Names, ", "))
+	}
+	fmt.Println()
+
+	if r.Query != nil {
+		fmt.Printf("Map:      %s\n", r.Query.Map)
+		if len(r.Query.Plugins) > 0 {
+			fmt.Printf("Plugins:  %s\n", strings.Join(r.Query.Plugins, ", "))
+		}
+	} else if r.QueryError != "" {
+		fmt.Printf("Query:    unavailable (%s)\n", r.QueryError)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/daemon"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+)
+
+const (
+	stopPollInterval = 500 * time.Millisecond
+	stopGracePeriod  = 45 * time.Second
+)
+
+func runStop(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("stop", "stop", "Asks the running launcher to stop the server gracefully and waits for it to exit.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stopped, err := stopLauncher(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if !stopped {
+		logger.Info("Launcher is not running")
+	}
+	return nil
+}
+
+func runRestart(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("restart", "restart", "Stops the running launcher, if any, and starts the server in this process.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// startServer enters work_dir itself, possibly after restarting into a
+	// launcher update, so the working directory must not change before it.
+	if _, err := stopLauncher(ctx, cfg); err != nil {
+		return err
+	}
+	return startServer(ctx, cfg)
+}
+
+// stopLauncher asks the launcher recorded in the PID file to stop, over its
+// console socket if possible, and waits for it to exit. It reports whether a
+// launcher was running. Paths are resolved against work_dir without
+// changing the working directory.
+func stopLauncher(ctx context.Context, cfg *config.Config) (bool, error) {
+	pidFile := inWorkDir(cfg, cfg.PIDFile)
+	pid, running := pidfile.Running(pidFile)
+	if !running {
+		return false, nil
+	}
+
+	logger.Info("Stopping launcher (pid %d)...", pid)
+	if err := daemon.Stop(inWorkDir(cfg, cfg.SocketFile)); err != nil {
+		logger.Debug("Console socket stop failed, signalling instead: %v", err)
+		if err := terminate(pid); err != nil {
+			return true, err
+		}
+	}
+
+	timeout := time.Duration(cfg.StopTimeout)*time.Second + stopGracePeriod
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, running := pidfile.Running(pidFile); !running {
+			logger.Info("Launcher stopped")
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-time.After(stopPollInterval):
+		}
+	}
+	return true, fmt.Errorf("launcher (pid %d) did not stop within %s", pid, timeout)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/daemon"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/pidfile"
+)
+
+func TestStopLauncherRelativeWorkDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	before, _ := os.Getwd()
+
+	cfg := &config.Config{WorkDir: filepath.Join("servers", "a"), PIDFile: "launcher.pid", SocketFile: "launcher.sock", StopTimeout: 1}
+	if err := os.MkdirAll(cfg.WorkDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stand in for a launcher running in work_dir: stopping it removes its
+	// PID file, as the real launcher does on exit.
+	pidPath := filepath.Join(cfg.WorkDir, cfg.PIDFile)
+	if err := pidfile.Write(pidPath); err != nil {
+		t.Fatal(err)
+	}
+	ctl, err := daemon.Listen(filepath.Join(cfg.WorkDir, cfg.SocketFile), console.New(nil), func() {
+		pidfile.Remove(pidPath)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctl.Close()
+
+	stopped, err := stopLauncher(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stopped {
+		t.Error("expected the launcher in work_dir to be found and stopped")
+	}
+	if got, _ := os.Getwd(); got != before {
+		t.Errorf("working directory changed to %s", got)
+	}
+}
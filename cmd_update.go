@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/history"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+func runUpdate(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("update", "update [flags]", "Updates the server JAR to the latest build and reports new launcher releases.")
+	checkOnly := fs.Bool("check", false, "Only report available updates")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if utils.Offline() {
+		return fmt.Errorf("update needs network access, which offline mode disables")
+	}
+
+	update.SetGitHubToken(cfg.GitHubToken)
+	checkLauncherUpdate(ctx, cfg.AutoUpdateLauncher && !*checkOnly)
+
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	jarFile, err := utils.FindJarFile(cfg.ServerType)
+	if err != nil {
+		return err
+	}
+	if jarFile == "" {
+		return fmt.Errorf("no server JAR found; run 'paper-launcher download' first")
+	}
+
+	if *checkOnly {
+		if history.Held(cfg.VersionsDir) == filepath.Base(jarFile) {
+			logger.Info("%s was chosen with rollback; automatic updates are held", jarFile)
+		}
+		upd, err := download.CheckUpdate(ctx, jarFile)
+		if err != nil {
+			return fmt.Errorf("failed to check for server updates: %w", err)
+		}
+		switch {
+		case upd.Available:
+			logUpdate(upd)
+		case upd.SkipReason != "":
+			logger.Info("%s is kept: %s", jarFile, upd.SkipReason)
+		default:
+			logger.Info("%s is up to date", jarFile)
+		}
+		return nil
+	}
+
+	// Running the command is the confirmation, and ends a rollback hold.
+	if held := history.Held(cfg.VersionsDir); held != "" {
+		if err := history.Release(cfg.VersionsDir); err != nil {
+			return err
+		}
+		logger.Info("Resuming updates for %s", held)
+	}
+	cfg.OnUpdate = config.PolicyUpdate
+	_, err = validateAndUpdateJar(ctx, jarFile, cfg)
+	return err
+}
+
+func runDownload(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := newFlagSet("download", "download", "Downloads the latest server JAR for server_type and minecraft_version without starting the server.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := enterWorkDir(cfg); err != nil {
+		return err
+	}
+
+	jarFile, err := download.DownloadJar(ctx, cfg.MinecraftVersion)
+	if err != nil {
+		return err
+	}
+	logger.Info("Server JAR ready: %s", jarFile)
+	return nil
+}
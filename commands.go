@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, args []string) error
+
+	// noConfig commands run without loading (or creating) config.yaml.
+	noConfig bool
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "start", summary: "Prepare the server JAR and run the server (default)", run: runStart},
+		{name: "stop", summary: "Stop a running launcher gracefully", run: runStop},
+		{name: "attach", summary: "Open the console of a running launcher", run: runAttach},
+		{name: "restart", summary: "Stop a running launcher and start the server again", run: runRestart},
+		{name: "status", summary: "Show launcher and server status", run: runStatus},
+		{name: "backup", summary: "Back up the world folders now", run: runBackup},
+		{name: "restore", summary: "Restore worlds from a backup archive", run: runRestore},
+		{name: "update", summary: "Update the server JAR to the latest build", run: runUpdate},
+		{name: "rollback", summary: "Switch back to a previous server JAR", run: runRollback},
+		{name: "download", summary: "Download the server JAR without starting it", run: runDownload},
+		{name: "cache", summary: "List or prune the shared download cache", run: runCache},
+		{name: "doctor", summary: "Check Java, memory, JAR and server settings", run: runDoctor},
+		{name: "config", summary: "Show, validate or locate the configuration", run: runConfig},
+		{name: "rcon", summary: "Send commands to the server over RCON", run: runRCON},
+		{name: "help", summary: "Show help for a command", run: runHelp, noConfig: true},
+	}
+}
+
+// findCommand resolves the command named by the first argument.
+// Bare invocation keeps the original behaviour of starting the server.
+func findCommand(args []string) (*command, []string, error) {
+	if len(args) == 0 {
+		return lookupCommand("start"), nil, nil
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		return nil, nil, fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd, args[1:], nil
+}
+
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// newFlagSet creates the flag set for a command with a consistent help layout.
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: paper-launcher [global flags] %s\n\n%s\n", usage, description)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: paper-launcher [global flags] [command] [flags]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(out, "\nGlobal flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nRun 'paper-launcher help <command>' for command flags.")
+}
+
+func runHelp(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return nil
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil || cmd.name == "help" {
+		printUsage()
+		return fmt.Errorf("unknown command: %s", strings.Join(args, " "))
+	}
+	// Every command prints its usage and returns flag.ErrHelp for -h
+	// before it touches the configuration.
+	return cmd.run(ctx, cfg, []string{"-h"})
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setDetachAttr starts the background launcher in a new session so it is
+// not tied to the terminal and survives the user logging out.
+func setDetachAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const detachedProcess = 0x00000008
+
+// setDetachAttr starts the background launcher without a console so that
+// closing the window it was started from does not stop it.
+func setDetachAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -2,11 +2,14 @@ package backup
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
@@ -18,7 +21,12 @@ const (
 	backupTimeLayout = "2006-01-02_15-04-05"
 )
 
-func PerformBackup(worlds []string, backupDir string, retentionCount int) error {
+func PerformBackup(worlds []string, backupDir string, retention Retention) error {
+	return performBackup(context.Background(), worlds, backupDir, retention)
+}
+
+// performBackup is PerformBackup that stops archiving when ctx is done.
+func performBackup(ctx context.Context, worlds []string, backupDir string, retention Retention) error {
 	if backupDir == "" {
 		backupDir = "backups"
 	}
@@ -41,18 +49,37 @@ func PerformBackup(worlds []string, backupDir string, retentionCount int) error
 		return nil
 	}
 
-	timestamp := time.Now().Format(backupTimeLayout)
-	backupFile := filepath.Join(backupDir, fmt.Sprintf("backup-%s.zip", timestamp))
-
-	logger.Info("Creating backup: %s", backupFile)
+	created := Archive{Name: "backup-" + time.Now().Format(backupTimeLayout), Format: format}
+	if format == FormatRepository {
+		repo := openRepository(backupDir)
+		created.Path = repo.snapshotPath(created.Name)
+		logger.Info("Creating backup: %s in %s", created.Name, repo.dir)
+		if err := repo.create(ctx, created.Name, existingWorlds); err != nil {
+			return err
+		}
+	} else {
+		created.Name += ".zip"
+		created.Path = filepath.Join(backupDir, created.Name)
+		logger.Info("Creating backup: %s", created.Path)
+		if err := createZip(ctx, created.Path, existingWorlds); err != nil {
+			if removeErr := os.Remove(created.Path); removeErr != nil && !os.IsNotExist(removeErr) {
+				logger.Warn("Failed to remove incomplete backup: %v", removeErr)
+			}
+			return err
+		}
+	}
 
-	if err := createZip(backupFile, existingWorlds); err != nil {
-		return err
+	// Older backups are only rotated out once the new one is known to be good.
+	if _, err := Verify(created.Path); err != nil {
+		if removeErr := remove(created); removeErr != nil {
+			logger.Warn("Failed to remove broken backup: %v", removeErr)
+		}
+		return fmt.Errorf("backup %s failed verification: %w", created.Name, err)
 	}
 
-	logger.Info("Backup created successfully")
+	logger.Info("Backup created and verified successfully")
 
-	if err := rotateBackups(backupDir, retentionCount); err != nil {
+	if _, err := Prune(backupDir, retention, false); err != nil {
 		logger.Warn("Failed to rotate backups: %v", err)
 	}
 
@@ -70,23 +97,21 @@ func filterExistingWorlds(worlds []string) []string {
 	return result
 }
 
-func createZip(targetFile string, worlds []string) error {
+// createZip archives the worlds into targetFile, followed by a manifest of
+// the archived files.
+func createZip(ctx context.Context, targetFile string, worlds []string) (err error) {
 	zipFile, err := os.Create(targetFile)
 	if err != nil {
 		return fmt.Errorf("failed to create zip file: %w", err)
 	}
 	defer func() {
-		if err := zipFile.Close(); err != nil {
-			logger.Warn("Failed to close zip file: %v", err)
+		if closeErr := zipFile.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close zip file: %w", closeErr)
 		}
 	}()
 
 	archive := zip.NewWriter(zipFile)
-	defer func() {
-		if err := archive.Close(); err != nil {
-			logger.Warn("Failed to close zip archive: %v", err)
-		}
-	}()
+	m := newManifest(worlds)
 
 	for _, world := range worlds {
 		if _, err := os.Stat(world); os.IsNotExist(err) {
@@ -97,6 +122,9 @@ func createZip(targetFile string, worlds []string) error {
 			if err != nil {
 				return fmt.Errorf("failed to walk directory: %w", err)
 			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 
 			if info.Name() == "session.lock" || strings.HasSuffix(info.Name(), ".tmp") {
 				return nil
@@ -108,6 +136,7 @@ func createZip(targetFile string, worlds []string) error {
 			}
 
 			header.Name = filepath.ToSlash(path)
+			file := manifestFile{Path: header.Name, Dir: info.IsDir(), ModTime: info.ModTime()}
 
 			if info.IsDir() {
 				header.Name += "/"
@@ -121,22 +150,29 @@ func createZip(targetFile string, worlds []string) error {
 			}
 
 			if info.IsDir() {
+				m.Files = append(m.Files, file)
 				return nil
 			}
 
-			file, err := os.Open(path)
+			src, err := os.Open(path)
 			if err != nil {
 				return fmt.Errorf("failed to open file: %w", err)
 			}
 
+			h := sha256.New()
 			buf := make([]byte, backupBufSize)
-			_, err = io.CopyBuffer(writer, file, buf)
-			if closeErr := file.Close(); closeErr != nil {
+			file.Size, err = io.CopyBuffer(io.MultiWriter(writer, h), src, buf)
+			if closeErr := src.Close(); closeErr != nil {
 				if err == nil {
 					err = fmt.Errorf("failed to close file: %w", closeErr)
 				}
 			}
-			return err
+			if err != nil {
+				return err
+			}
+			file.SHA256 = hex.EncodeToString(h.Sum(nil))
+			m.Files = append(m.Files, file)
+			return nil
 		})
 
 		if err != nil {
@@ -144,54 +180,16 @@ func createZip(targetFile string, worlds []string) error {
 		}
 	}
 
-	return nil
-}
-
-func rotateBackups(backupDir string, limit int) error {
-	if limit <= 0 {
-		return nil
-	}
-
-	files, err := os.ReadDir(backupDir)
+	writer, err := archive.Create(manifestName)
 	if err != nil {
-		return fmt.Errorf("failed to read backup directory: %w", err)
-	}
-
-	type backupInfo struct {
-		entry   os.DirEntry
-		modTime time.Time
-	}
-
-	backups := make([]backupInfo, 0, len(files))
-	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), "backup-") && strings.HasSuffix(file.Name(), ".zip") {
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-			backups = append(backups, backupInfo{
-				entry:   file,
-				modTime: info.ModTime(),
-			})
-		}
+		return fmt.Errorf("failed to create zip entry: %w", err)
 	}
-
-	if len(backups) <= limit {
-		return nil
+	if err := json.NewEncoder(writer).Encode(m); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
 	}
-
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].modTime.Before(backups[j].modTime)
-	})
-
-	toDelete := len(backups) - limit
-	for i := 0; i < toDelete; i++ {
-		path := filepath.Join(backupDir, backups[i].entry.Name())
-		logger.Info("Deleting old backup: %s", backups[i].entry.Name())
-		if err := os.Remove(path); err != nil {
-			return fmt.Errorf("failed to remove backup file: %w", err)
-		}
+	// Close writes the central directory; without it the archive is unreadable.
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip archive: %w", err)
 	}
-
 	return nil
 }
@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.zip")
+	writeArchive(t, archive, map[string]string{
+		"world/level.dat":          "old level",
+		"world_nether/DIM-1/r.mca": "nether",
+	})
+
+	if err := os.MkdirAll(filepath.Join(dir, "world"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "world", "level.dat"), []byte("new level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	safety, err := Restore(archive, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(safety) != 1 {
+		t.Fatalf("expected one safety copy, got %v", safety)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "world", "level.dat"))
+	if err != nil || string(data) != "old level" {
+		t.Errorf("expected restored level.dat, got %q (%v)", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(safety[0], "level.dat"))
+	if err != nil || string(data) != "new level" {
+		t.Errorf("expected safety copy of level.dat, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "world_nether", "DIM-1", "r.mca")); err != nil {
+		t.Errorf("expected nether to be restored: %v", err)
+	}
+}
+
+func TestRestoreRejectsZipSlip(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "server")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "evil.zip")
+	writeArchive(t, archive, map[string]string{"../evil.txt": "pwned"})
+
+	if _, err := Restore(archive, dest); err == nil {
+		t.Error("expected error for path traversal")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("file was written outside the destination")
+	}
+}
+
+func TestSafeEntryName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"world/region/r.0.0.mca", true},
+		{"world/", true},
+		{"world/../../etc/passwd", false},
+		{"/etc/passwd", false},
+		{"C:/Windows/win.ini", false},
+		{"..\\evil", false},
+	}
+	for _, tt := range tests {
+		if _, err := safeEntryName(tt.name); (err == nil) != tt.ok {
+			t.Errorf("safeEntryName(%q) error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestRestoreWorlds(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.zip")
+	writeArchive(t, archive, map[string]string{
+		"world/level.dat":          "old level",
+		"world_nether/DIM-1/r.mca": "old nether",
+	})
+	for name, content := range map[string]string{
+		"world/level.dat":          "new level",
+		"world_nether/DIM-1/r.mca": "new nether",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := RestoreWorlds(archive, dir, []string{"world_the_end"}); err == nil {
+		t.Fatal("expected an error for a world missing from the backup")
+	}
+
+	safety, err := RestoreWorlds(archive, dir, []string{"world_nether"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(safety) != 1 {
+		t.Fatalf("expected one safety copy, got %v", safety)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "world_nether", "DIM-1", "r.mca"))
+	if string(data) != "old nether" {
+		t.Errorf("expected restored nether, got %q", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "world", "level.dat"))
+	if string(data) != "new level" {
+		t.Errorf("expected overworld to be left alone, got %q", data)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"backup-2024-01-02_03-04-05.zip",
+		"backup-2024-03-01_00-00-00.zip",
+		"backup-custom.zip",
+		"notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The name, not the modification time, dates a backup.
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(dir, "backup-2024-03-01_00-00-00.zip"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range archives {
+		names = append(names, a.Name)
+	}
+	want := []string{"backup-custom.zip", "backup-2024-03-01_00-00-00.zip", "backup-2024-01-02_03-04-05.zip"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local); !archives[2].Created.Equal(want) {
+		t.Errorf("got created %v, want %v", archives[2].Created, want)
+	}
+
+	if archives, err := List(filepath.Join(dir, "missing")); err != nil || len(archives) != 0 {
+		t.Errorf("expected no backups for a missing directory, got %v (%v)", archives, err)
+	}
+}
@@ -0,0 +1,122 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/rcon"
+)
+
+// savedRegex matches the message the server prints when "save-all" completes.
+var savedRegex = regexp.MustCompile(`Saved the (game|world)$`)
+
+// logPrefix matches the "[12:00:00 INFO]: " or "[12:00:00] [Server
+// thread/INFO]: " in front of a server log message.
+const logPrefix = `^(?:\[[^\]]*\] )*\[[^\]]*\]: `
+
+// saveTimeout bounds how long a live backup waits for the worlds to be saved.
+var saveTimeout = 5 * time.Minute
+
+var ErrSaveTimeout = errors.New("server did not finish saving the worlds")
+
+// Server runs commands on a running Minecraft server.
+type Server interface {
+	// Exec sends command. When done is not nil it also waits until the
+	// server replies with a message matching done.
+	Exec(ctx context.Context, command string, done *regexp.Regexp) error
+}
+
+// LiveBackup backs up the worlds of a running server. World saving is turned
+// off and the worlds are flushed to disk before they are archived, and saving
+// is always turned back on afterwards, including on failure or cancellation.
+func LiveBackup(ctx context.Context, srv Server, worlds []string, backupDir string, retention Retention) (err error) {
+	if err := srv.Exec(ctx, "save-off", nil); err != nil {
+		return fmt.Errorf("failed to turn off world saving: %w", err)
+	}
+	defer func() {
+		// ctx may already be cancelled, but saving must be re-enabled anyway.
+		if onErr := srv.Exec(context.Background(), "save-on", nil); onErr != nil {
+			logger.Error("Failed to turn world saving back on, run 'save-on' on the server: %v", onErr)
+			if err == nil {
+				err = fmt.Errorf("failed to turn world saving back on: %w", onErr)
+			}
+		}
+	}()
+
+	saveCtx, cancel := context.WithTimeout(ctx, saveTimeout)
+	defer cancel()
+	if err := srv.Exec(saveCtx, "save-all flush", savedRegex); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return ErrSaveTimeout
+		}
+		return fmt.Errorf("failed to save the worlds: %w", err)
+	}
+
+	return performBackup(ctx, worlds, backupDir, retention)
+}
+
+// ConsoleServer runs commands through the launcher's own server console.
+func ConsoleServer(con *console.Console) Server {
+	return consoleServer{con}
+}
+
+type consoleServer struct {
+	con *console.Console
+}
+
+func (s consoleServer) Exec(ctx context.Context, command string, done *regexp.Regexp) error {
+	if done == nil {
+		return s.con.Send(command)
+	}
+	// done must match the whole message after the log prefix, so a player
+	// cannot complete the wait by saying the same words in chat.
+	line, err := regexp.Compile(logPrefix + `(?:` + done.String() + `)`)
+	if err != nil {
+		return err
+	}
+	// Subscribe before sending so the reply cannot be missed.
+	matched := make(chan struct{}, 1)
+	unsubscribe := s.con.Subscribe(line, func([]string) {
+		select {
+		case matched <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	if err := s.con.Send(command); err != nil {
+		return err
+	}
+	select {
+	case <-matched:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RCONServer runs commands over RCON, where the reply to a command is its
+// output.
+func RCONServer(client *rcon.Client) Server {
+	return rconServer{client}
+}
+
+type rconServer struct {
+	client *rcon.Client
+}
+
+func (s rconServer) Exec(ctx context.Context, command string, done *regexp.Regexp) error {
+	resp, err := s.client.Execute(ctx, command)
+	if err != nil {
+		return err
+	}
+	if done != nil && !done.MatchString(resp) {
+		return fmt.Errorf("unexpected reply to %q: %s", command, resp)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/rcon"
+)
+
+// fakeServer records the commands of a live backup.
+type fakeServer struct {
+	commands []string
+	fail     map[string]error
+}
+
+func (s *fakeServer) Exec(ctx context.Context, command string, done *regexp.Regexp) error {
+	s.commands = append(s.commands, command)
+	if err := s.fail[command]; err != nil {
+		return err
+	}
+	if done != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestLiveBackup(t *testing.T) {
+	errRefused := errors.New("refused")
+
+	tests := []struct {
+		name         string
+		fail         map[string]error
+		cancel       bool
+		wantCommands []string
+		wantBackup   bool
+		wantErr      bool
+	}{
+		{"success", nil, false, []string{"save-off", "save-all flush", "save-on"}, true, false},
+		{"save fails", map[string]error{"save-all flush": errRefused}, false, []string{"save-off", "save-all flush", "save-on"}, false, true},
+		{"cancelled", nil, true, []string{"save-off", "save-all flush", "save-on"}, false, true},
+		{"save-off fails", map[string]error{"save-off": errRefused}, false, []string{"save-off"}, false, true},
+		{"save-on fails", map[string]error{"save-on": errRefused}, false, []string{"save-off", "save-all flush", "save-on"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			world := filepath.Join(dir, "world")
+			if err := os.MkdirAll(world, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(world, "level.dat"), []byte("level"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			backupDir := filepath.Join(dir, "backups")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			srv := &fakeServer{fail: tt.fail}
+			err := LiveBackup(ctx, srv, []string{world}, backupDir, Retention{Count: 10})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LiveBackup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(srv.commands, tt.wantCommands) {
+				t.Errorf("commands = %q, want %q", srv.commands, tt.wantCommands)
+			}
+			files, _ := filepath.Glob(filepath.Join(backupDir, "backup-*.zip"))
+			if got := len(files) == 1; got != tt.wantBackup {
+				t.Errorf("backup created = %v, want %v", got, tt.wantBackup)
+			}
+		})
+	}
+}
+
+func TestConsoleServer(t *testing.T) {
+	con := console.New(nil)
+	stdinR, stdinW := io.Pipe()
+	unbind := con.Bind(stdinW)
+	defer unbind()
+
+	// A stand-in server that answers "save-all flush" like Minecraft does.
+	outR, outW := io.Pipe()
+	go con.Pump(outR)
+	go func() {
+		scanner := bufio.NewScanner(stdinR)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "save-all") {
+				io.WriteString(outW, "[12:00:00] [Server thread/INFO]: Saving the game (this may take a moment!)\n")
+				io.WriteString(outW, "[12:00:00] [Server thread/INFO]: Saved the game\n")
+			} else {
+				io.WriteString(outW, "[12:00:00 INFO]: <Steve> Saved the game\n")
+			}
+		}
+	}()
+	defer outW.Close()
+	defer stdinW.Close()
+
+	srv := ConsoleServer(con)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Exec(ctx, "save-all flush", savedRegex); err != nil {
+		t.Fatalf("expected the save to complete, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Exec(ctx, "list", savedRegex); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a chat message not to complete the save, got %v", err)
+	}
+}
+
+func TestSavedRegex(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  bool
+	}{
+		{"Saved the game", true},
+		{"Saving the game (this may take a moment!)Saved the game", true},
+		{"Saving the game (this may take a moment!)\nSaved the world", true},
+		{"Saving the game (this may take a moment!)", false},
+	}
+	for _, tt := range tests {
+		if got := savedRegex.MatchString(tt.reply); got != tt.want {
+			t.Errorf("savedRegex.MatchString(%q) = %v, want %v", tt.reply, got, tt.want)
+		}
+	}
+}
+
+// serveRCON answers RCON requests on l like a Minecraft server, passing
+// each command to handle.
+func serveRCON(l net.Listener, handle func(command string) string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size, id, typ int32
+		if binary.Read(r, binary.LittleEndian, &size) != nil || size < 10 {
+			return
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return
+		}
+		id = int32(binary.LittleEndian.Uint32(data[0:4]))
+		typ = int32(binary.LittleEndian.Uint32(data[4:8]))
+		body := strings.TrimRight(string(data[8:]), "\x00")
+
+		var reply string
+		switch typ {
+		case 3: // login
+		case 2: // command
+			reply = handle(body)
+		default:
+			reply = "Unknown request 0"
+		}
+		replyType := int32(0)
+		if typ == 3 {
+			replyType = 2
+		}
+		packet := make([]byte, 14+len(reply))
+		binary.LittleEndian.PutUint32(packet[0:], uint32(10+len(reply)))
+		binary.LittleEndian.PutUint32(packet[4:], uint32(id))
+		binary.LittleEndian.PutUint32(packet[8:], uint32(replyType))
+		copy(packet[12:], reply)
+		conn.Write(packet)
+	}
+}
+
+func TestRCONServerSlowSave(t *testing.T) {
+	oldTimeout := rcon.DefaultTimeout
+	rcon.DefaultTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { rcon.DefaultTimeout = oldTimeout })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var mu sync.Mutex
+	var commands []string
+	go serveRCON(l, func(command string) string {
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		if command == "save-all flush" {
+			// Flushing a large world takes longer than the RCON timeout.
+			time.Sleep(200 * time.Millisecond)
+			return "Saving the game (this may take a moment!)Saved the game"
+		}
+		return ""
+	})
+
+	ctx := context.Background()
+	client, err := rcon.Dial(ctx, l.Addr().String(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	dir := t.TempDir()
+	world := filepath.Join(dir, "world")
+	if err := os.MkdirAll(world, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(world, "level.dat"), []byte("level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LiveBackup(ctx, RCONServer(client), []string{world}, filepath.Join(dir, "backups"), Retention{}); err != nil {
+		t.Fatalf("expected the live backup to wait for the save, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"save-off", "save-all flush", "save-on"}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+}
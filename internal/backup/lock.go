@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// lockName is the file locked while a process adds to or cleans up the
+// repository. Without it, gc in one process could delete the chunks that a
+// backup in another has stored but not yet listed in its snapshot.
+const lockName = "lock"
+
+// lockRetry is how often a backup waiting for the repository tries again.
+var lockRetry = 500 * time.Millisecond
+
+// tryLock locks the repository if no other backup holds it. The lock is
+// released by unlock, or by the operating system if the process dies.
+func (r *repository) tryLock() (unlock func(), ok bool, err error) {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create backup repository: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(r.dir, lockName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open repository lock: %w", err)
+	}
+	if ok, err := lockFile(f); !ok {
+		f.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to lock backup repository: %w", err)
+		}
+		return nil, false, nil
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, true, nil
+}
+
+// lock locks the repository, waiting while another backup holds it.
+func (r *repository) lock(ctx context.Context) (unlock func(), err error) {
+	waiting := false
+	for {
+		unlock, ok, err := r.tryLock()
+		if err != nil || ok {
+			return unlock, err
+		}
+		if !waiting {
+			logger.Info("Waiting for another backup to finish with the repository")
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+}
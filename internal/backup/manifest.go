@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestName is the manifest entry at the root of a zip backup.
+const manifestName = "backup-manifest.json"
+
+var launcherVersion, serverJar string
+
+// SetVersions records the launcher version and the server JAR in use in the
+// manifest of every backup made afterwards.
+func SetVersions(launcher, jar string) {
+	launcherVersion = launcher
+	serverJar = jar
+}
+
+// manifest describes the contents of a backup. In the repository it is the
+// snapshot itself, and its files also list their chunks.
+type manifest struct {
+	Created   time.Time      `json:"created"`
+	Launcher  string         `json:"launcher_version,omitempty"`
+	ServerJar string         `json:"server_jar,omitempty"`
+	Worlds    []string       `json:"worlds"`
+	Files     []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Path    string    `json:"path"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	SHA256  string    `json:"sha256,omitempty"`
+	ModTime time.Time `json:"mod_time"`
+	Chunks  []string  `json:"chunks,omitempty"`
+}
+
+func newManifest(worlds []string) *manifest {
+	return &manifest{
+		Created:   time.Now(),
+		Launcher:  launcherVersion,
+		ServerJar: serverJar,
+		Worlds:    worlds,
+	}
+}
+
+func (m *manifest) size() int64 {
+	var total int64
+	for _, f := range m.Files {
+		total += f.Size
+	}
+	return total
+}
+
+func readManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filepath.Base(path), err)
+	}
+	return &m, nil
+}
+
+// Result summarises a verified backup.
+type Result struct {
+	Files int
+	Size  int64
+	// Manifest is false for zip backups made before manifests were added,
+	// which can only be checked against the CRCs in the archive.
+	Manifest bool
+}
+
+// Verify re-reads every file of a backup and checks it against the backup's
+// manifest. A truncated or damaged backup fails.
+func Verify(archive string) (*Result, error) {
+	if isSnapshotPath(archive) {
+		return verifySnapshot(archive)
+	}
+	return verifyZip(archive)
+}
+
+func verifyZip(archive string) (*Result, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[manifestName]
+	if !ok {
+		res := &Result{}
+		for _, f := range reader.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			// archive/zip checks the CRC once an entry is read to the end.
+			n, _, err := hashEntry(f.Open)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			res.Files++
+			res.Size += n
+		}
+		return res, nil
+	}
+
+	m, err := readZipManifest(mf)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Manifest: true}
+	for _, want := range m.Files {
+		name := want.Path
+		if want.Dir {
+			name += "/"
+		}
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing from the backup", want.Path)
+		}
+		delete(files, name)
+		if want.Dir {
+			continue
+		}
+		if err := checkFile(want, f.Open); err != nil {
+			return nil, err
+		}
+		res.Files++
+		res.Size += want.Size
+	}
+	delete(files, manifestName)
+	for name := range files {
+		return nil, fmt.Errorf("%s is not listed in the manifest", name)
+	}
+	return res, nil
+}
+
+func readZipManifest(f *zip.File) (*manifest, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	defer r.Close()
+	var m manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func verifySnapshot(path string) (*Result, error) {
+	m, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	repo := repositoryOf(path)
+	res := &Result{Manifest: true}
+	for _, want := range m.Files {
+		if want.Dir {
+			continue
+		}
+		chunks := want.Chunks
+		open := func() (io.ReadCloser, error) {
+			return &chunkReader{repo: repo, chunks: chunks}, nil
+		}
+		if err := checkFile(want, open); err != nil {
+			return nil, err
+		}
+		res.Files++
+		res.Size += want.Size
+	}
+	return res, nil
+}
+
+// checkFile reads a file from a backup and compares it with its manifest
+// entry.
+func checkFile(want manifestFile, open func() (io.ReadCloser, error)) error {
+	size, sum, err := hashEntry(open)
+	if err != nil {
+		return fmt.Errorf("%s: %w", want.Path, err)
+	}
+	if size != want.Size {
+		return fmt.Errorf("%s: size is %d bytes, manifest says %d", want.Path, size, want.Size)
+	}
+	if want.SHA256 != "" && sum != want.SHA256 {
+		return fmt.Errorf("%s: checksum mismatch", want.Path)
+	}
+	return nil
+}
+
+func hashEntry(open func() (io.ReadCloser, error)) (int64, string, error) {
+	r, err := open()
+	if err != nil {
+		return 0, "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	n, err := io.CopyBuffer(h, r, make([]byte, backupBufSize))
+	if err != nil {
+		return n, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifyZip(t *testing.T) {
+	chdirTemp(t)
+	writeWorldFile(t, "world/level.dat", []byte("level"))
+	writeWorldFile(t, "world/region/r.0.0.mca", []byte("region"))
+	SetVersions("v1.2.3", "paper-1.21.4-120.jar")
+	t.Cleanup(func() { SetVersions("", "") })
+
+	if err := PerformBackup([]string{"world"}, "backups", Retention{Count: 10}); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := List("backups")
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("expected one backup, got %v (%v)", archives, err)
+	}
+	path := archives[0].Path
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Manifest || res.Files != 2 || res.Size != int64(len("level")+len("region")) {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m *manifest
+	for _, f := range reader.File {
+		if f.Name == manifestName {
+			m, err = readZipManifest(f)
+		}
+	}
+	reader.Close()
+	if m == nil || err != nil {
+		t.Fatalf("expected a manifest in the backup (%v)", err)
+	}
+	if m.Launcher != "v1.2.3" || m.ServerJar != "paper-1.21.4-120.jar" || !reflect.DeepEqual(m.Worlds, []string{"world"}) {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	worlds, err := Worlds(path)
+	if err != nil || !reflect.DeepEqual(worlds, []string{"world"}) {
+		t.Errorf("the manifest should not be listed as a world, got %v (%v)", worlds, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join("backups", "backup-truncated.zip")
+	if err := os.WriteFile(truncated, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(truncated); err == nil {
+		t.Error("expected a truncated backup to fail verification")
+	}
+}
+
+func TestVerifyZipAgainstManifest(t *testing.T) {
+	manifestFor := func(sum string) string {
+		return `{"worlds":["world"],"files":[{"path":"world/level.dat","size":5,"sha256":"` + sum + `"}]}`
+	}
+	// SHA-256 of "level".
+	const levelSum = "0081779c287d567d9ca622f4c0cc2ede819b0cc7f286a5f01d8c3c0178191ad6"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantErr  bool
+		manifest bool
+	}{
+		{"no manifest", map[string]string{"world/level.dat": "level"}, false, false},
+		{"matches manifest", map[string]string{"world/level.dat": "level", manifestName: manifestFor(levelSum)}, false, true},
+		{"size mismatch", map[string]string{"world/level.dat": "lev", manifestName: manifestFor(levelSum)}, true, true},
+		{"checksum mismatch", map[string]string{"world/level.dat": "LEVEL", manifestName: manifestFor(levelSum)}, true, true},
+		{"missing file", map[string]string{manifestName: manifestFor(levelSum)}, true, true},
+		{"unlisted file", map[string]string{"world/level.dat": "lev", "world/extra": "x", manifestName: `{"files":[]}`}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "backup.zip")
+			writeArchive(t, path, tt.files)
+			res, err := Verify(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && res.Manifest != tt.manifest {
+				t.Errorf("Manifest = %v, want %v", res.Manifest, tt.manifest)
+			}
+		})
+	}
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	chdirTemp(t)
+	writeWorldFile(t, "world/level.dat", []byte("level"))
+	repo := openRepository("backups")
+	if err := repo.create(context.Background(), "backup-2024-01-01_00-00-00", []string{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	path := repo.snapshotPath("backup-2024-01-01_00-00-00")
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Manifest || res.Files != 1 || res.Size != 5 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	chunks, _ := filepath.Glob(filepath.Join(repo.dir, chunksDir, "*", "*"))
+	if err := os.WriteFile(chunks[0], []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(path); err == nil {
+		t.Error("expected a snapshot with a corrupt chunk to fail verification")
+	}
+}
@@ -0,0 +1,383 @@
+package backup
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// Values of backup_format.
+const (
+	FormatZip        = "zip"
+	FormatRepository = "repository"
+)
+
+const (
+	repositoryDir = "repository"
+	chunksDir     = "chunks"
+	snapshotsDir  = "snapshots"
+	snapshotExt   = ".json"
+
+	// Region files are rewritten in place, so fixed-size chunks line up
+	// between backups and unchanged parts of a file are stored only once.
+	chunkSize = 1 << 20
+)
+
+var format = FormatZip
+
+// SetFormat selects how new backups are stored: as zip archives or as
+// snapshots in a deduplicated repository inside the backup directory.
+func SetFormat(f string) {
+	if f == "" {
+		f = FormatZip
+	}
+	format = f
+}
+
+// repository stores backups as snapshots that share their chunks.
+type repository struct {
+	dir string
+}
+
+func openRepository(backupDir string) *repository {
+	return &repository{dir: filepath.Join(backupDir, repositoryDir)}
+}
+
+// isSnapshotPath reports whether path names a snapshot manifest rather than
+// a zip archive.
+func isSnapshotPath(path string) bool {
+	return filepath.Ext(path) == snapshotExt && filepath.Base(filepath.Dir(path)) == snapshotsDir
+}
+
+// repositoryOf returns the repository a snapshot manifest belongs to.
+func repositoryOf(snapshotPath string) *repository {
+	return &repository{dir: filepath.Dir(filepath.Dir(snapshotPath))}
+}
+
+func (r *repository) snapshotPath(name string) string {
+	return filepath.Join(r.dir, snapshotsDir, name+snapshotExt)
+}
+
+func (r *repository) chunkPath(sum string) string {
+	return filepath.Join(r.dir, chunksDir, sum[:2], sum)
+}
+
+// create stores the worlds as a new snapshot. Chunks that are already in the
+// repository are not written again. The manifest is written last, so an
+// interrupted backup leaves only unreferenced chunks behind, which the next
+// backup reuses or retention removes.
+func (r *repository) create(ctx context.Context, name string, worlds []string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snap := newManifest(worlds)
+	var total, added int64
+
+	for _, world := range worlds {
+		err := filepath.Walk(world, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("failed to walk directory: %w", err)
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if info.Name() == "session.lock" || strings.HasSuffix(info.Name(), ".tmp") {
+				return nil
+			}
+
+			file := manifestFile{Path: filepath.ToSlash(path), ModTime: info.ModTime()}
+			if info.IsDir() {
+				file.Dir = true
+			} else {
+				n, err := r.storeFile(path, &file)
+				if err != nil {
+					return err
+				}
+				total += file.Size
+				added += n
+			}
+			snap.Files = append(snap.Files, file)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backup %s: %w", world, err)
+		}
+	}
+
+	if err := r.writeSnapshot(name, snap); err != nil {
+		return err
+	}
+	logger.Info("Stored %s of world data, %s of it new", formatSize(total), formatSize(added))
+	return nil
+}
+
+// storeFile splits the file at path into chunks, stores the ones the
+// repository does not have yet and records them in file. It returns the
+// uncompressed size of the newly stored chunks.
+func (r *repository) storeFile(path string, file *manifestFile) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var added int64
+	whole := sha256.New()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			id := hex.EncodeToString(sum[:])
+			stored, werr := r.storeChunk(id, buf[:n])
+			if werr != nil {
+				return 0, werr
+			}
+			if stored {
+				added += int64(n)
+			}
+			whole.Write(buf[:n])
+			file.Size += int64(n)
+			file.Chunks = append(file.Chunks, id)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			file.SHA256 = hex.EncodeToString(whole.Sum(nil))
+			return added, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
+
+// storeChunk writes a compressed chunk unless it is already stored.
+func (r *repository) storeChunk(sum string, data []byte) (bool, error) {
+	path := r.chunkPath(sum)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), sum+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("failed to create chunk: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// Region files are already compressed, so favour speed.
+	w, _ := flate.NewWriter(tmp, flate.BestSpeed)
+	_, err = w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	return true, nil
+}
+
+// readChunk returns the contents of a chunk after checking them against
+// its hash.
+func (r *repository) readChunk(sum string) ([]byte, error) {
+	if !validChunkID(sum) {
+		return nil, fmt.Errorf("invalid chunk id: %q", sum)
+	}
+	f, err := os.Open(r.chunkPath(sum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", sum[:12], err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(flate.NewReader(f), chunkSize+1)); err != nil {
+		return nil, fmt.Errorf("chunk %s is corrupt: %w", sum[:12], err)
+	}
+	actual := sha256.Sum256(buf.Bytes())
+	if hex.EncodeToString(actual[:]) != sum {
+		return nil, fmt.Errorf("chunk %s is corrupt: checksum mismatch", sum[:12])
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *repository) hasChunk(sum string) error {
+	if !validChunkID(sum) {
+		return fmt.Errorf("invalid chunk id: %q", sum)
+	}
+	if _, err := os.Stat(r.chunkPath(sum)); err != nil {
+		return fmt.Errorf("chunk %s is missing: %w", sum[:12], err)
+	}
+	return nil
+}
+
+func validChunkID(sum string) bool {
+	b, err := hex.DecodeString(sum)
+	return err == nil && len(b) == sha256.Size && sum == strings.ToLower(sum)
+}
+
+func (r *repository) writeSnapshot(name string, snap *manifest) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	path := r.snapshotPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// snapshots returns the names of the snapshots in the repository.
+func (r *repository) snapshots() ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(r.dir, snapshotsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	var names []string
+	for _, f := range files {
+		if name, ok := strings.CutSuffix(f.Name(), snapshotExt); ok && !f.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// entries returns the contents of a snapshot for restoring.
+func (r *repository) entries(snap *manifest) []entry {
+	entries := make([]entry, 0, len(snap.Files))
+	for _, f := range snap.Files {
+		f := f
+		e := entry{name: f.Path, dir: f.Dir, modified: f.ModTime}
+		if !f.Dir {
+			e.open = func() (io.ReadCloser, error) {
+				return &chunkReader{repo: r, chunks: f.Chunks}, nil
+			}
+			e.check = func() error {
+				for _, c := range f.Chunks {
+					if err := r.hasChunk(c); err != nil {
+						return fmt.Errorf("%s: %w", f.Path, err)
+					}
+				}
+				return nil
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// gc removes the chunks that no snapshot refers to any more, and chunks
+// left half-written by an interrupted backup. It does nothing while another
+// backup is using the repository; the next prune cleans up instead.
+func (r *repository) gc() (int, error) {
+	unlock, ok, err := r.tryLock()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		logger.Info("Another backup is using the repository, leaving unused chunks for the next prune")
+		return 0, nil
+	}
+	defer unlock()
+
+	names, err := r.snapshots()
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[string]bool)
+	for _, name := range names {
+		// A snapshot that cannot be read may still need its chunks.
+		snap, err := readManifest(r.snapshotPath(name))
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range snap.Files {
+			for _, c := range f.Chunks {
+				used[c] = true
+			}
+		}
+	}
+
+	removed := 0
+	err = filepath.WalkDir(filepath.Join(r.dir, chunksDir), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || used[d.Name()] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove chunk: %w", err)
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// chunkReader reads a file back from its chunks.
+type chunkReader struct {
+	repo   *repository
+	chunks []string
+	buf    []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if len(c.chunks) == 0 {
+			return 0, io.EOF
+		}
+		data, err := c.repo.readChunk(c.chunks[0])
+		if err != nil {
+			return 0, err
+		}
+		c.buf = data
+		c.chunks = c.chunks[1:]
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *chunkReader) Close() error {
+	return nil
+}
+
+func formatSize(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+}
@@ -0,0 +1,225 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeWorldFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countChunks(t *testing.T, repo *repository) int {
+	t.Helper()
+	chunks, err := filepath.Glob(filepath.Join(repo.dir, chunksDir, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(chunks)
+}
+
+func TestRepositorySnapshots(t *testing.T) {
+	chdirTemp(t)
+	region := make([]byte, 2*chunkSize+100)
+	rand.New(rand.NewSource(1)).Read(region)
+	writeWorldFile(t, "world/region/r.0.0.mca", region)
+	writeWorldFile(t, "world/level.dat", []byte("first"))
+	writeWorldFile(t, "world/session.lock", []byte("lock"))
+	if err := os.MkdirAll("world/data", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := openRepository("backups")
+	ctx := context.Background()
+	if err := repo.create(ctx, "backup-2024-01-01_00-00-00", []string{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := countChunks(t, repo); got != 4 {
+		t.Fatalf("expected 4 chunks after the first snapshot, got %d", got)
+	}
+
+	// Only the changed file is stored again.
+	writeWorldFile(t, "world/level.dat", []byte("second"))
+	if err := repo.create(ctx, "backup-2024-01-02_00-00-00", []string{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := countChunks(t, repo); got != 5 {
+		t.Fatalf("expected 5 chunks after the second snapshot, got %d", got)
+	}
+
+	archives, err := List("backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 2 || archives[0].Name != "backup-2024-01-02_00-00-00" || archives[0].Format != FormatRepository {
+		t.Fatalf("unexpected backups: %+v", archives)
+	}
+	if want := int64(len(region) + len("second")); archives[0].Size != want {
+		t.Errorf("got size %d, want %d", archives[0].Size, want)
+	}
+
+	old, err := Find("backups", "backup-2024-01-01_00-00-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(old.Path, "."); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("world/level.dat"); string(data) != "first" {
+		t.Errorf("expected the first level.dat, got %q", data)
+	}
+	if data, _ := os.ReadFile("world/region/r.0.0.mca"); !bytes.Equal(data, region) {
+		t.Error("restored region file differs")
+	}
+	if info, err := os.Stat("world/data"); err != nil || !info.IsDir() {
+		t.Errorf("expected the empty data folder to be restored: %v", err)
+	}
+	if _, err := os.Stat("world/session.lock"); !os.IsNotExist(err) {
+		t.Errorf("session.lock should not be backed up: %v", err)
+	}
+
+	// Rotation drops the old snapshot and the chunk only it used.
+	if _, err := Prune("backups", Retention{Count: 1}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := countChunks(t, repo); got != 4 {
+		t.Errorf("expected 4 chunks after rotation, got %d", got)
+	}
+	if _, err := Find("backups", old.Name); err == nil {
+		t.Error("expected the old snapshot to be rotated out")
+	}
+}
+
+func TestRepositoryRestoreDamaged(t *testing.T) {
+	chdirTemp(t)
+	writeWorldFile(t, "world/level.dat", []byte("saved"))
+	repo := openRepository("backups")
+	if err := repo.create(context.Background(), "backup-2024-01-01_00-00-00", []string{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	writeWorldFile(t, "world/level.dat", []byte("current"))
+
+	chunks, _ := filepath.Glob(filepath.Join(repo.dir, chunksDir, "*", "*"))
+	if len(chunks) != 1 {
+		t.Fatalf("expected one chunk, got %v", chunks)
+	}
+	snapshot := repo.snapshotPath("backup-2024-01-01_00-00-00")
+
+	tests := []struct {
+		name   string
+		damage func() error
+	}{
+		{"corrupt chunk", func() error { return os.WriteFile(chunks[0], []byte("garbage"), 0644) }},
+		{"missing chunk", func() error { return os.Remove(chunks[0]) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.damage(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Restore(snapshot, "."); err == nil {
+				t.Fatal("expected restore of a damaged snapshot to fail")
+			}
+			if data, _ := os.ReadFile("world/level.dat"); string(data) != "current" {
+				t.Errorf("expected the current world to be kept, got %q", data)
+			}
+		})
+	}
+}
+
+func TestRepositoryGCLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		locked  bool
+		removed int
+	}{
+		{"unlocked removes unused and partial chunks", false, 2},
+		{"locked by another backup removes nothing", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			writeWorldFile(t, "world/level.dat", []byte("level"))
+			repo := openRepository("backups")
+			if err := repo.create(context.Background(), "backup-2024-01-01_00-00-00", []string{"world"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// A chunk left half-written by an interrupted backup, and one
+			// left unreferenced.
+			partial := filepath.Join(repo.dir, chunksDir, "ab", "abcd.123.tmp")
+			unused := filepath.Join(repo.dir, chunksDir, "ab", "abcd")
+			for _, name := range []string{partial, unused} {
+				writeWorldFile(t, name, []byte("chunk"))
+			}
+
+			if tt.locked {
+				unlock, ok, err := openRepository("backups").tryLock()
+				if err != nil || !ok {
+					t.Fatalf("failed to lock repository: %v", err)
+				}
+				defer unlock()
+			}
+
+			removed, err := repo.gc()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != tt.removed {
+				t.Errorf("expected %d chunks to be removed, got %d", tt.removed, removed)
+			}
+			for _, name := range []string{partial, unused} {
+				if _, err := os.Stat(name); os.IsNotExist(err) == tt.locked {
+					t.Errorf("%s: unexpected stat result %v", filepath.Base(name), err)
+				}
+			}
+		})
+	}
+}
+
+func TestRepositoryCreateWaitsForLock(t *testing.T) {
+	chdirTemp(t)
+	writeWorldFile(t, "world/level.dat", []byte("level"))
+	repo := openRepository("backups")
+	unlock, ok, err := repo.tryLock()
+	if err != nil || !ok {
+		t.Fatalf("failed to lock repository: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := repo.create(ctx, "backup-2024-01-01_00-00-00", []string{"world"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected create to wait for the lock, got %v", err)
+	}
+
+	unlock()
+	if err := repo.create(context.Background(), "backup-2024-01-01_00-00-00", []string{"world"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,365 @@
+package backup
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// Archive is a backup in the backup directory: a zip archive or a snapshot
+// in the backup repository.
+type Archive struct {
+	Name    string
+	Path    string
+	Format  string
+	Size    int64
+	Created time.Time
+}
+
+// List returns the backups in backupDir, newest first.
+func List(backupDir string) ([]Archive, error) {
+	files, err := os.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var archives []Archive
+	for _, f := range files {
+		if f.IsDir() || !isBackupName(f.Name()) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		created, ok := parseBackupTime(f.Name())
+		if !ok {
+			created = info.ModTime()
+		}
+		archives = append(archives, Archive{
+			Name:    f.Name(),
+			Path:    filepath.Join(backupDir, f.Name()),
+			Format:  FormatZip,
+			Size:    info.Size(),
+			Created: created,
+		})
+	}
+
+	repo := openRepository(backupDir)
+	names, err := repo.snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		path := repo.snapshotPath(name)
+		snap, err := readManifest(path)
+		if err != nil {
+			logger.Warn("Skipping unreadable snapshot: %v", err)
+			continue
+		}
+		created, ok := parseBackupTime(name)
+		if !ok {
+			created = snap.Created
+		}
+		archives = append(archives, Archive{
+			Name:    name,
+			Path:    path,
+			Format:  FormatRepository,
+			Size:    snap.size(),
+			Created: created,
+		})
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].Created.After(archives[j].Created)
+	})
+	return archives, nil
+}
+
+// Find returns the backup in backupDir with the given name.
+func Find(backupDir, name string) (Archive, error) {
+	archives, err := List(backupDir)
+	if err != nil {
+		return Archive{}, err
+	}
+	for _, a := range archives {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return Archive{}, fmt.Errorf("no backup named %s in %s", name, backupDir)
+}
+
+// remove deletes a backup. Chunks of a removed snapshot stay in the
+// repository until its next garbage collection.
+func remove(a Archive) error {
+	if err := os.Remove(a.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove backup %s: %w", a.Name, err)
+	}
+	return nil
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, "backup-") && strings.HasSuffix(name, ".zip")
+}
+
+// parseBackupTime reads the creation time from a backup-<time>.zip or
+// backup-<time> snapshot name.
+func parseBackupTime(name string) (time.Time, bool) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, "backup-"), ".zip")
+	t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	return t, err == nil
+}
+
+// entry is a file or directory stored in a backup.
+type entry struct {
+	name     string
+	dir      bool
+	modified time.Time
+	open     func() (io.ReadCloser, error)
+	// check, if set, reports data missing from the backup before anything
+	// is extracted.
+	check func() error
+}
+
+// openBackup reads the entries of a zip archive or a repository snapshot.
+func openBackup(archive string) ([]entry, func() error, error) {
+	if isSnapshotPath(archive) {
+		snap, err := readManifest(archive)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repositoryOf(archive).entries(snap), func() error { return nil }, nil
+	}
+
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	entries := make([]entry, 0, len(reader.File))
+	for _, f := range reader.File {
+		entries = append(entries, entry{
+			name:     f.Name,
+			dir:      f.FileInfo().IsDir(),
+			modified: f.Modified,
+			open:     f.Open,
+		})
+	}
+	return entries, reader.Close, nil
+}
+
+// Worlds returns the world folders contained in a backup.
+func Worlds(archive string) ([]string, error) {
+	entries, closeBackup, err := openBackup(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBackup()
+	return archiveWorlds(entries)
+}
+
+// Restore extracts the worlds contained in archive, a zip archive or a
+// snapshot manifest, into destDir. World
+// folders that already exist are moved aside first and put back if the
+// extraction fails. It returns the paths of the moved-aside folders.
+func Restore(archive, destDir string) ([]string, error) {
+	return RestoreWorlds(archive, destDir, nil)
+}
+
+// RestoreWorlds is Restore limited to the named worlds; nil restores all of
+// them. Other world folders are left untouched.
+func RestoreWorlds(archive, destDir string, only []string) ([]string, error) {
+	entries, closeBackup, err := openBackup(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBackup()
+
+	worlds, err := archiveWorlds(entries)
+	if err != nil {
+		return nil, err
+	}
+	if len(worlds) == 0 {
+		return nil, fmt.Errorf("backup contains no worlds: %s", archive)
+	}
+	if only != nil {
+		if worlds, err = selectWorlds(worlds, only); err != nil {
+			return nil, err
+		}
+	}
+	selected := make(map[string]bool, len(worlds))
+	for _, w := range worlds {
+		selected[w] = true
+	}
+	var files []entry
+	for _, e := range entries {
+		name, _ := safeEntryName(e.name)
+		if world, _, _ := strings.Cut(name, "/"); selected[world] {
+			files = append(files, e)
+		}
+	}
+	for _, e := range files {
+		if e.check != nil {
+			if err := e.check(); err != nil {
+				return nil, fmt.Errorf("backup is incomplete: %w", err)
+			}
+		}
+	}
+
+	suffix := ".before-restore-" + time.Now().Format(backupTimeLayout)
+	moved := make(map[string]string, len(worlds))
+	rollback := func() {
+		for _, world := range worlds {
+			target := filepath.Join(destDir, world)
+			if err := os.RemoveAll(target); err != nil {
+				logger.Warn("Failed to remove partially restored %s: %v", world, err)
+			}
+			if aside, ok := moved[world]; ok {
+				if err := os.Rename(aside, target); err != nil {
+					logger.Warn("Failed to put back %s: %v", world, err)
+				}
+			}
+		}
+	}
+
+	for _, world := range worlds {
+		target := filepath.Join(destDir, world)
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			continue
+		}
+		aside := target + suffix
+		if err := os.Rename(target, aside); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to move %s aside: %w", world, err)
+		}
+		logger.Info("Moved current %s to %s", world, aside)
+		moved[world] = aside
+	}
+
+	for _, e := range files {
+		if err := extractFile(e, destDir); err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+
+	safety := make([]string, 0, len(moved))
+	for _, aside := range moved {
+		safety = append(safety, aside)
+	}
+	sort.Strings(safety)
+	return safety, nil
+}
+
+// selectWorlds checks that every requested world is in the archive.
+func selectWorlds(available, only []string) ([]string, error) {
+	selected := make([]string, 0, len(only))
+	for _, w := range only {
+		w = strings.Trim(filepath.ToSlash(strings.TrimSpace(w)), "/")
+		found := false
+		for _, a := range available {
+			if a == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("backup does not contain world %q (it has: %s)", w, strings.Join(available, ", "))
+		}
+		selected = append(selected, w)
+	}
+	return selected, nil
+}
+
+// archiveWorlds returns the top-level folders in a backup archive.
+func archiveWorlds(entries []entry) ([]string, error) {
+	seen := make(map[string]bool)
+	worlds := []string{}
+	for _, e := range entries {
+		name, err := safeEntryName(e.name)
+		if err != nil {
+			return nil, err
+		}
+		if name == manifestName {
+			continue
+		}
+		world, _, _ := strings.Cut(name, "/")
+		if world != "" && !seen[world] {
+			seen[world] = true
+			worlds = append(worlds, world)
+		}
+	}
+	sort.Strings(worlds)
+	return worlds, nil
+}
+
+// safeEntryName rejects entries that would be written outside the
+// destination directory (zip slip).
+func safeEntryName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("unsafe path in backup: %s", name)
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("unsafe path in backup: %s", name)
+	}
+	return clean, nil
+}
+
+func extractFile(e entry, destDir string) error {
+	name, err := safeEntryName(e.name)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+
+	if e.dir {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	src, err := e.open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s from backup: %w", e.name, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	buf := make([]byte, backupBufSize)
+	_, err = io.CopyBuffer(dst, src, buf)
+	if closeErr := dst.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close file: %w", closeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", e.name, err)
+	}
+
+	if !e.modified.IsZero() {
+		if err := os.Chtimes(target, e.modified, e.modified); err != nil {
+			logger.Debug("Failed to restore modification time of %s: %v", target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// Retention decides which backups are kept when old ones are pruned. The
+// zero Retention keeps every backup.
+type Retention struct {
+	// Count keeps the newest Count backups. It is ignored when GFS is set.
+	Count int
+	// GFS keeps every backup of the last 24 hours, then the newest backup of
+	// each day for a week, of each week for four weeks and of each month
+	// for a year.
+	GFS bool
+	// MaxSize, if positive, also deletes the oldest backups until the rest
+	// take up at most MaxSize bytes on disk. The newest backup is always kept.
+	MaxSize int64
+}
+
+// gfsTier keeps the newest backup of each period, e.g. each day, since a
+// point in time.
+type gfsTier struct {
+	since  time.Time
+	period func(time.Time) string
+}
+
+// keep returns the backups the policy keeps, newest first. backups must be
+// sorted newest first.
+func (r Retention) keep(backups []Archive, now time.Time) []Archive {
+	if !r.GFS {
+		if r.Count > 0 && len(backups) > r.Count {
+			return backups[:r.Count]
+		}
+		return backups
+	}
+
+	tiers := []gfsTier{
+		{now.AddDate(0, 0, -7), func(t time.Time) string { return t.Format("2006-01-02") }},
+		{now.AddDate(0, 0, -28), func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{now.AddDate(-1, 0, 0), func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	seen := make([]map[string]bool, len(tiers))
+	for i := range seen {
+		seen[i] = make(map[string]bool)
+	}
+
+	recent := now.Add(-24 * time.Hour)
+	var kept []Archive
+	for i, b := range backups {
+		keep := i == 0 || b.Created.After(recent)
+		for j, tier := range tiers {
+			if b.Created.Before(tier.since) {
+				continue
+			}
+			if p := tier.period(b.Created); !seen[j][p] {
+				seen[j][p] = true
+				keep = true
+			}
+		}
+		if keep {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// Prune deletes the backups in backupDir that the retention policy does not
+// keep and returns them, oldest first. With dryRun nothing is deleted.
+// Backups are dated by the time in their name; backups whose names carry no
+// time are never pruned.
+func Prune(backupDir string, r Retention, dryRun bool) ([]Archive, error) {
+	all, err := List(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	var managed []Archive
+	for _, a := range all {
+		if _, ok := parseBackupTime(a.Name); ok {
+			managed = append(managed, a)
+		}
+	}
+
+	kept := r.keep(managed, time.Now())
+	if r.MaxSize > 0 {
+		if kept, err = fitSize(backupDir, kept, r.MaxSize); err != nil {
+			return nil, err
+		}
+	}
+
+	keep := make(map[string]bool, len(kept))
+	for _, a := range kept {
+		keep[a.Path] = true
+	}
+	var expired []Archive
+	for i := len(managed) - 1; i >= 0; i-- {
+		if !keep[managed[i].Path] {
+			expired = append(expired, managed[i])
+		}
+	}
+	if dryRun || len(expired) == 0 {
+		return expired, nil
+	}
+
+	removedSnapshot := false
+	for i, b := range expired {
+		logger.Info("Deleting old backup: %s", b.Name)
+		if err := remove(b); err != nil {
+			return expired[:i], err
+		}
+		if b.Format == FormatRepository {
+			removedSnapshot = true
+		}
+	}
+	if removedSnapshot {
+		n, err := openRepository(backupDir).gc()
+		if err != nil {
+			return expired, fmt.Errorf("failed to clean up backup repository: %w", err)
+		}
+		logger.Debug("Removed %d unused chunks from the backup repository", n)
+	}
+	return expired, nil
+}
+
+// fitSize drops the oldest backups from kept until the rest fit in limit
+// bytes. Snapshots share chunks, so their size is that of the chunks the
+// remaining snapshots use rather than the size of the files they hold.
+func fitSize(backupDir string, kept []Archive, limit int64) ([]Archive, error) {
+	repo := openRepository(backupDir)
+	chunkSizes, err := repo.chunkSizes()
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(map[string][]string)
+	for _, a := range kept {
+		if a.Format != FormatRepository {
+			continue
+		}
+		m, err := readManifest(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range m.Files {
+			chunks[a.Path] = append(chunks[a.Path], f.Chunks...)
+		}
+	}
+
+	usage := func(backups []Archive) int64 {
+		var total int64
+		used := make(map[string]bool)
+		for _, a := range backups {
+			if a.Format != FormatRepository {
+				total += a.Size
+				continue
+			}
+			for _, c := range chunks[a.Path] {
+				if !used[c] {
+					used[c] = true
+					total += chunkSizes[c]
+				}
+			}
+		}
+		return total
+	}
+
+	for len(kept) > 1 && usage(kept) > limit {
+		kept = kept[:len(kept)-1]
+	}
+	return kept, nil
+}
+
+// chunkSizes returns the size on disk of every chunk in the repository.
+func (r *repository) chunkSizes() (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := filepath.WalkDir(filepath.Join(r.dir, chunksDir), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sizes[d.Name()] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup repository: %w", err)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionKeep(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+	at := func(name string, year int, month time.Month, day, hour int) Archive {
+		return Archive{Name: name, Created: time.Date(year, month, day, hour, 0, 0, 0, time.Local)}
+	}
+	backups := []Archive{
+		at("a", 2025, 6, 15, 11),
+		at("b", 2025, 6, 15, 1),
+		at("c", 2025, 6, 14, 20), // within 24 hours
+		at("d", 2025, 6, 14, 8),  // same day as c
+		at("e", 2025, 6, 12, 10), // newest of its day
+		at("f", 2025, 6, 12, 9),
+		at("g", 2025, 6, 5, 10), // newest of its week
+		at("h", 2025, 6, 3, 10),
+		at("i", 2025, 5, 20, 10), // newest of its week, still within four weeks
+		at("j", 2025, 5, 10, 10), // same month as i
+		at("k", 2025, 4, 30, 10), // newest of its month
+		at("l", 2024, 7, 1, 10),
+		at("m", 2024, 6, 1, 10), // older than a year
+	}
+
+	names := func(archives []Archive) []string {
+		var out []string
+		for _, a := range archives {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		retention Retention
+		backups   []Archive
+		want      []string
+	}{
+		{"count", Retention{Count: 3}, backups, []string{"a", "b", "c"}},
+		{"count larger than backups", Retention{Count: 20}, backups[:2], []string{"a", "b"}},
+		{"gfs", Retention{Count: 3, GFS: true}, backups, []string{"a", "b", "c", "e", "g", "i", "k", "l"}},
+		{"gfs keeps the newest backup", Retention{GFS: true}, backups[len(backups)-1:], []string{"m"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(tt.retention.keep(tt.backups, now))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"backup-2024-01-01_00-00-00.zip": 300,
+		"backup-2024-01-02_00-00-00.zip": 300,
+		"backup-2024-01-03_00-00-00.zip": 300,
+		"backup-custom.zip":              300,
+	}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Backups are dated by their names, not their modification times.
+	newest := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(dir, "backup-2024-01-03_00-00-00.zip"), newest, newest); err != nil {
+		t.Fatal(err)
+	}
+
+	retention := Retention{Count: 10, MaxSize: 700}
+	expired, err := Prune(dir, retention, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].Name != "backup-2024-01-01_00-00-00.zip" {
+		t.Fatalf("unexpected dry run result: %+v", expired)
+	}
+	if _, err := os.Stat(expired[0].Path); err != nil {
+		t.Fatalf("dry run deleted a backup: %v", err)
+	}
+
+	if _, err := Prune(dir, retention, false); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, a := range archives {
+		left = append(left, a.Name)
+	}
+	want := []string{"backup-custom.zip", "backup-2024-01-03_00-00-00.zip", "backup-2024-01-02_00-00-00.zip"}
+	if !reflect.DeepEqual(left, want) {
+		t.Errorf("got %v, want %v", left, want)
+	}
+}
+
+func TestPruneMaxSizeCountsSharedChunksOnce(t *testing.T) {
+	chdirTemp(t)
+	writeWorldFile(t, "world/level.dat", make([]byte, 1000))
+	repo := openRepository("backups")
+	for _, name := range []string{"backup-2024-01-01_00-00-00", "backup-2024-01-02_00-00-00"} {
+		if err := repo.create(context.Background(), name, []string{"world"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sizes, err := repo.chunkSizes()
+	if err != nil || len(sizes) != 1 {
+		t.Fatalf("expected one shared chunk, got %v (%v)", sizes, err)
+	}
+	var stored int64
+	for _, size := range sizes {
+		stored = size
+	}
+
+	expired, err := Prune("backups", Retention{MaxSize: stored}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("both snapshots fit in the size of their shared chunk, got %+v expired", expired)
+	}
+	expired, err = Prune("backups", Retention{MaxSize: stored - 1}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].Name != "backup-2024-01-01_00-00-00" {
+		t.Errorf("expected the older snapshot to exceed the size cap, got %+v", expired)
+	}
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+const (
+	// Disabled is the cache_dir value that turns the cache off.
+	Disabled = "off"
+
+	appDir = "minecraft-server-launcher"
+	jarExt = ".jar"
+	idxExt = ".json"
+)
+
+// DefaultDir returns the per-user cache directory shared by every server
+// directory on the host.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, appDir, "jars"), nil
+}
+
+// Cache stores downloaded server JARs under their SHA-256, so servers on the
+// same host share a single download of each build.
+type Cache struct {
+	dir string
+}
+
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Entry is a cached JAR.
+type Entry struct {
+	SHA256 string `json:"-"`
+	Name   string `json:"name"`
+	// URL is where the JAR was downloaded from, which tells apart builds
+	// that share a name, e.g. Fabric JARs made by different installers.
+	URL      string    `json:"url,omitempty"`
+	Size     int64     `json:"-"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+func (c *Cache) path(sum string) string {
+	return filepath.Join(c.dir, strings.ToLower(sum)+jarExt)
+}
+
+// Install places the cached JAR with the given SHA-256 at dest. It reports
+// false if the JAR is not cached. A cached file that no longer matches its
+// hash is dropped from the cache.
+func (c *Cache) Install(sum, dest string) (bool, error) {
+	if !validSum(sum) {
+		return false, fmt.Errorf("invalid SHA-256: %q", sum)
+	}
+	src := c.path(sum)
+	if _, err := os.Stat(src); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := utils.ValidateChecksum(src, sum); err != nil {
+		c.Remove(sum)
+		return false, fmt.Errorf("dropped corrupt cache entry: %w", err)
+	}
+	if err := linkOrCopy(src, dest); err != nil {
+		return false, fmt.Errorf("failed to install %s from cache: %w", filepath.Base(dest), err)
+	}
+	entry := c.readIndex(sum)
+	if entry.Name == "" {
+		entry.Name = filepath.Base(dest)
+	}
+	entry.LastUsed = time.Now()
+	if err := c.writeIndex(sum, entry); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// Add stores the file at path, whose SHA-256 is sum and which was
+// downloaded from url, in the cache.
+func (c *Cache) Add(path, sum, url string) error {
+	if !validSum(sum) {
+		return fmt.Errorf("invalid SHA-256: %q", sum)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if _, err := os.Stat(c.path(sum)); os.IsNotExist(err) {
+		if err := linkOrCopy(path, c.path(sum)); err != nil {
+			return fmt.Errorf("failed to add %s to cache: %w", filepath.Base(path), err)
+		}
+	}
+	return c.writeIndex(sum, Entry{Name: filepath.Base(path), URL: url, LastUsed: time.Now()})
+}
+
+// List returns the cached JARs, most recently used first.
+func (c *Cache) List() ([]Entry, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		sum, ok := strings.CutSuffix(f.Name(), jarExt)
+		if !ok || !validSum(sum) || f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		e := c.readIndex(sum)
+		e.SHA256 = sum
+		e.Size = info.Size()
+		if e.LastUsed.IsZero() {
+			e.LastUsed = info.ModTime()
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Remove deletes a cached JAR. Server directories that hard-linked it keep
+// their copy.
+func (c *Cache) Remove(sum string) error {
+	if err := os.Remove(c.path(sum)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache entry: %w", err)
+	}
+	if err := os.Remove(c.indexPath(sum)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache entry: %w", err)
+	}
+	return nil
+}
+
+// Prune removes the JARs that have not been used for maxAge.
+func (c *Cache) Prune(maxAge time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	var removed []Entry
+	for _, e := range entries {
+		if e.LastUsed.After(cutoff) {
+			continue
+		}
+		if err := c.Remove(e.SHA256); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+func validSum(sum string) bool {
+	b, err := hex.DecodeString(sum)
+	return err == nil && len(b) == sha256.Size
+}
+
+func (c *Cache) indexPath(sum string) string {
+	return filepath.Join(c.dir, strings.ToLower(sum)+idxExt)
+}
+
+func (c *Cache) readIndex(sum string) Entry {
+	var e Entry
+	if data, err := os.ReadFile(c.indexPath(sum)); err == nil {
+		json.Unmarshal(data, &e)
+	}
+	return e
+}
+
+func (c *Cache) writeIndex(sum string, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.indexPath(sum), data, 0644); err != nil {
+		return fmt.Errorf("failed to update cache index: %w", err)
+	}
+	return nil
+}
+
+// linkOrCopy makes dest a hard link to src, or a copy when src is on
+// another filesystem. dest is replaced atomically.
+func linkOrCopy(src, dest string) error {
+	tmp := dest + ".tmp"
+	os.Remove(tmp)
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	defer os.Remove(tmp) // left behind if dest already was a link to src
+	return os.Rename(tmp, dest)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
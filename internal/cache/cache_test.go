@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeJar(t *testing.T, path, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(content))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAddInstall(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "cache"))
+	server1, server2 := t.TempDir(), t.TempDir()
+
+	jar := filepath.Join(server1, "paper-1.21.4-20.jar")
+	sum := writeJar(t, jar, "Manifest-Version: 1.0\n")
+
+	if ok, err := c.Install(sum, filepath.Join(server2, "paper-1.21.4-20.jar")); ok || err != nil {
+		t.Fatalf("expected a miss on an empty cache, got %v (%v)", ok, err)
+	}
+	if err := c.Add(jar, sum, "https://example.com/paper-1.21.4-20.jar"); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(server2, "paper-1.21.4-20.jar")
+	ok, err := c.Install(sum, dest)
+	if !ok || err != nil {
+		t.Fatalf("expected a hit, got %v (%v)", ok, err)
+	}
+	want, _ := os.ReadFile(jar)
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, want) {
+		t.Error("installed JAR does not match")
+	}
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].SHA256 != sum || entries[0].Name != "paper-1.21.4-20.jar" ||
+		entries[0].URL != "https://example.com/paper-1.21.4-20.jar" || entries[0].Size != int64(len(want)) {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	if _, err := c.Install("../../etc/passwd", dest); err == nil {
+		t.Error("expected an error for an invalid hash")
+	}
+}
+
+func TestInstallCorrupt(t *testing.T) {
+	c := New(t.TempDir())
+	jar := filepath.Join(t.TempDir(), "paper-1.21.4-20.jar")
+	sum := writeJar(t, jar, "Manifest-Version: 1.0\n")
+	if err := c.Add(jar, sum, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite the cached file in place, as a server writing to a
+	// hard-linked JAR would.
+	writeJar(t, c.path(sum), "tampered\n")
+
+	ok, err := c.Install(sum, filepath.Join(t.TempDir(), "paper-1.21.4-20.jar"))
+	if ok || err == nil {
+		t.Fatalf("expected the corrupt entry to be rejected, got %v (%v)", ok, err)
+	}
+	if entries, _ := c.List(); len(entries) != 0 {
+		t.Errorf("corrupt entry should be removed, got %+v", entries)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := New(t.TempDir())
+	dir := t.TempDir()
+
+	oldJar := filepath.Join(dir, "paper-1.21.3-10.jar")
+	oldSum := writeJar(t, oldJar, "old\n")
+	newJar := filepath.Join(dir, "paper-1.21.4-20.jar")
+	newSum := writeJar(t, newJar, "new\n")
+	for jar, sum := range map[string]string{oldJar: oldSum, newJar: newSum} {
+		if err := c.Add(jar, sum, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.writeIndex(oldSum, Entry{Name: "paper-1.21.3-10.jar", LastUsed: time.Now().AddDate(0, 0, -60)}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.Prune(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].SHA256 != oldSum {
+		t.Fatalf("expected only the old JAR to be pruned, got %+v", removed)
+	}
+	if _, err := os.Stat(oldJar); err != nil {
+		t.Error("pruning must not touch server copies")
+	}
+
+	if removed, _ := c.Prune(0); len(removed) != 1 {
+		t.Errorf("expected the remaining JAR to be pruned, got %+v", removed)
+	}
+}
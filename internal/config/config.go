@@ -4,23 +4,65 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
 	"gopkg.in/yaml.v3"
 )
 
 const defaultConfig = `# 마인크래프트 서버 런처 설정
 # 처음 실행 시 자동으로 생성됩니다.
 
+# 서버 종류: paper | folia | purpur | fabric | vanilla | velocity | waterfall
+# velocity와 waterfall은 프록시이며 minecraft_version에 프록시 버전을 지정합니다.
+server_type: paper
+
 # 마인크래프트 버전 ("latest" 또는 "1.21.4" 같은 특정 버전)
 minecraft_version: "latest"
 
 # 서버 JAR 및 런처 자동 업데이트 여부
 auto_update: true
 auto_update_launcher: true
+# 런처 업데이트 채널: stable (정식 릴리스) | beta (프리릴리스 포함)
+launcher_update_channel: stable
+
+# 업데이트할 빌드 채널
+# default: 안정 빌드만 사용 (안정 빌드가 아직 없는 버전은 실험 빌드 사용)
+# stable-only: 안정 빌드만 사용 / experimental: 실험 빌드 포함 최신 빌드 사용
+build_channel: default
+# 특정 빌드로 고정 (예: 120). minecraft_version을 특정 버전으로 지정해야 합니다.
+pinned_build: ""
+
+# 네트워크에 접속하지 않고 로컬 및 캐시된 JAR만 사용 (업데이트 확인 생략)
+offline: false
+
+# 업데이트로 교체된 이전 서버 JAR을 보관할 개수 (rollback 명령으로 되돌릴 수 있습니다)
+versions_keep: 5
+
+# 질문 없이 처리할 동작 (systemd, 컨테이너 등 비대화형 환경용)
+# on_missing_jar: prompt | download | abort
+# on_checksum_mismatch: prompt | redownload | abort | ignore
+# on_update: prompt | update | skip (비워두면 auto_update 설정을 따릅니다)
+on_missing_jar: prompt
+on_checksum_mismatch: prompt
 
 # 서버 시작 전 월드 자동 백업 여부
 auto_backup: false
+# 서버 실행 중 자동 백업 주기(분). 0이면 사용하지 않습니다.
+# 백업하는 동안 save-off로 저장을 멈추고 끝나면 save-on으로 되돌립니다.
+backup_interval: 0
+
+# 백업 형식: zip | repository
+# repository: 파일을 조각으로 나눠 바뀐 조각만 저장하는 증분 백업 (큰 월드의 디스크 사용량 절약)
+backup_format: zip
+# 오래된 백업 정리 방식: count | gfs
+# count: 최근 backup_count개(기본 10개)만 보관
+# gfs: 24시간 내 백업은 모두, 이후 7일간 하루 1개, 4주간 주 1개, 12개월간 월 1개씩 보관
+backup_retention: count
+# 백업이 차지할 최대 용량(MB). 넘으면 오래된 백업부터 삭제합니다. 0이면 제한 없음.
+backup_max_size: 0
 
 # 백업할 월드 폴더 목록 (커스텀 월드 이름 사용 시 수정)
 backup_worlds:
@@ -36,20 +78,62 @@ max_ram: 0
 # 서버에 전달할 추가 인수
 server_args:
   - nogui
+
+# 서버가 비정상 종료되면 자동으로 재시작 (stop 명령으로 정상 종료한 경우 제외)
+auto_restart: true
+# 재시작 대기 시간(초). 연속으로 크래시하면 restart_max_delay까지 2배씩 늘어납니다.
+restart_delay: 5
+restart_max_delay: 300
+# crash_window(분) 안에 crash_limit번 크래시하면 재시작을 중단합니다.
+crash_limit: 5
+crash_window: 10
+
+# 런처 종료 시 서버 콘솔에 보낼 명령과 월드 저장을 기다릴 최대 시간(초)
+stop_command: "stop"
+stop_timeout: 60
+
+# 서버가 이 시간(초) 안에 시작을 완료하지 못하면 실패로 처리합니다.
+startup_timeout: 300
 `
 
 type Config struct {
-	MinecraftVersion string   `yaml:"minecraft_version"`
-	AutoUpdate       bool     `yaml:"auto_update"`
-	AutoBackup       bool     `yaml:"auto_backup"`
-	BackupCount        int      `yaml:"backup_count"`
-	BackupDir          string   `yaml:"backup_dir"`
-	BackupWorlds       []string `yaml:"backup_worlds"`
-	MinRAM             int      `yaml:"min_ram"`
-	MaxRAM             int      `yaml:"max_ram"`
-	UseZGC             bool     `yaml:"use_zgc"`
-	AutoRAMPercentage  int      `yaml:"auto_ram_percentage"`
-	ServerArgs         []string `yaml:"server_args"`
+	ServerType            string   `yaml:"server_type"` // paper | folia | purpur | fabric | vanilla | velocity | waterfall
+	MinecraftVersion      string   `yaml:"minecraft_version"`
+	AutoUpdate            bool     `yaml:"auto_update"`
+	AutoUpdateLauncher    bool     `yaml:"auto_update_launcher"`
+	LauncherUpdateChannel string   `yaml:"launcher_update_channel"` // stable | beta
+	BuildChannel          string   `yaml:"build_channel"`           // default | stable-only | experimental
+	PinnedBuild           string   `yaml:"pinned_build"`
+	VersionsKeep          int      `yaml:"versions_keep"`
+	Offline               bool     `yaml:"offline"`
+	AutoBackup            bool     `yaml:"auto_backup"`
+	BackupInterval        int      `yaml:"backup_interval"` // 분
+	BackupCount           int      `yaml:"backup_count"`
+	BackupDir             string   `yaml:"backup_dir"`
+	BackupFormat          string   `yaml:"backup_format"`    // zip | repository
+	BackupRetention       string   `yaml:"backup_retention"` // count | gfs
+	BackupMaxSize         int      `yaml:"backup_max_size"`  // MB
+	BackupWorlds          []string `yaml:"backup_worlds"`
+	MinRAM                int      `yaml:"min_ram"`
+	MaxRAM                int      `yaml:"max_ram"`
+	UseZGC                bool     `yaml:"use_zgc"`
+	AutoRAMPercentage     int      `yaml:"auto_ram_percentage"`
+	ServerArgs            []string `yaml:"server_args"`
+
+	OnMissingJar       string `yaml:"on_missing_jar"`       // prompt | download | abort
+	OnChecksumMismatch string `yaml:"on_checksum_mismatch"` // prompt | redownload | abort | ignore
+	OnUpdate           string `yaml:"on_update"`            // prompt | update | skip
+
+	AutoRestart     bool `yaml:"auto_restart"`
+	RestartDelay    int  `yaml:"restart_delay"`     // 초
+	RestartMaxDelay int  `yaml:"restart_max_delay"` // 초
+	CrashLimit      int  `yaml:"crash_limit"`
+	CrashWindow     int  `yaml:"crash_window"` // 분
+
+	StopCommand string `yaml:"stop_command"`
+	StopTimeout int    `yaml:"stop_timeout"` // 초
+
+	StartupTimeout int `yaml:"startup_timeout"` // 초
 
 	// 고급 옵션 — config.yaml에 직접 추가하거나 환경변수로 설정
 	GitHubToken   string `yaml:"github_token"`    // 권장: LAUNCHER_GITHUB_TOKEN 환경변수
@@ -57,6 +141,10 @@ type Config struct {
 	JavaPath      string `yaml:"java_path"`       // 환경변수: JAVA_PATH
 	LogFileEnable bool   `yaml:"log_file_enable"` // 로그 파일 저장 여부
 	LogFile       string `yaml:"log_file"`        // 환경변수: LOG_FILE
+	PIDFile       string `yaml:"pid_file"`        // 실행 중인 런처의 PID 파일 (작업 디렉토리 기준)
+	SocketFile    string `yaml:"socket_file"`     // attach/stop에 쓰이는 콘솔 소켓 (작업 디렉토리 기준)
+	VersionsDir   string `yaml:"versions_dir"`    // 이전 서버 JAR 보관 폴더 (작업 디렉토리 기준, 서버의 versions 폴더와 별개)
+	CacheDir      string `yaml:"cache_dir"`       // 서버 간 공유 다운로드 캐시 폴더 (off: 사용 안 함). 환경변수: LAUNCHER_CACHE_DIR
 }
 
 func Load(path string) (*Config, error) {
@@ -78,6 +166,21 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if cfg.ServerType == "" {
+		cfg.ServerType = defaultServerType
+	}
+	if cfg.BuildChannel == "" {
+		cfg.BuildChannel = download.BuildChannelDefault
+	}
+	if cfg.LauncherUpdateChannel == "" {
+		cfg.LauncherUpdateChannel = update.ChannelStable
+	}
+	if cfg.VersionsKeep == 0 {
+		cfg.VersionsKeep = defaultVersionsKeep
+	}
+	if cfg.VersionsDir == "" {
+		cfg.VersionsDir = defaultVersionsDir
+	}
 	if cfg.AutoRAMPercentage == 0 {
 		cfg.AutoRAMPercentage = defaultAutoRAMPercent
 	}
@@ -90,10 +193,59 @@ func Load(path string) (*Config, error) {
 	if cfg.BackupDir == "" {
 		cfg.BackupDir = defaultBackupDir
 	}
+	if cfg.BackupFormat == "" {
+		cfg.BackupFormat = BackupFormatZip
+	}
+	if cfg.BackupRetention == "" {
+		cfg.BackupRetention = BackupRetentionCount
+	}
 	if cfg.LogFile == "" {
 		cfg.LogFile = defaultLogFile
 	}
+	if cfg.OnMissingJar == "" {
+		cfg.OnMissingJar = PolicyPrompt
+	}
+	if cfg.OnChecksumMismatch == "" {
+		cfg.OnChecksumMismatch = PolicyPrompt
+	}
+	if cfg.OnUpdate == "" {
+		if cfg.AutoUpdate {
+			cfg.OnUpdate = PolicyUpdate
+		} else {
+			cfg.OnUpdate = PolicyPrompt
+		}
+	}
+	if cfg.PIDFile == "" {
+		cfg.PIDFile = defaultPIDFile
+	}
+	if cfg.SocketFile == "" {
+		cfg.SocketFile = defaultSocketFile
+	}
+	if cfg.RestartDelay == 0 {
+		cfg.RestartDelay = defaultRestartDelay
+	}
+	if cfg.RestartMaxDelay == 0 {
+		cfg.RestartMaxDelay = defaultRestartMaxDelay
+	}
+	if cfg.CrashLimit == 0 {
+		cfg.CrashLimit = defaultCrashLimit
+	}
+	if cfg.CrashWindow == 0 {
+		cfg.CrashWindow = defaultCrashWindow
+	}
+	if cfg.StopCommand == "" {
+		cfg.StopCommand = defaultStopCommand
+	}
+	if cfg.StopTimeout == 0 {
+		cfg.StopTimeout = defaultStopTimeout
+	}
+	if cfg.StartupTimeout == 0 {
+		cfg.StartupTimeout = defaultStartupTimeout
+	}
 
+	if v := os.Getenv("SERVER_TYPE"); v != "" {
+		cfg.ServerType = v
+	}
 	if v := os.Getenv("MINECRAFT_VERSION"); v != "" {
 		cfg.MinecraftVersion = v
 	}
@@ -106,6 +258,9 @@ func Load(path string) (*Config, error) {
 	if v := os.Getenv("LOG_FILE"); v != "" {
 		cfg.LogFile = v
 	}
+	if v := os.Getenv("LAUNCHER_CACHE_DIR"); v != "" {
+		cfg.CacheDir = v
+	}
 	if v := os.Getenv("LAUNCHER_GITHUB_TOKEN"); v != "" {
 		cfg.GitHubToken = v
 	} else if v := os.Getenv("GITHUB_TOKEN"); v != "" {
@@ -136,18 +291,72 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// ServerTypes lists the supported server_type values.
+var ServerTypes = []string{"paper", "folia", "purpur", "fabric", "vanilla", "velocity", "waterfall"}
+
+// Values of backup_format.
+const (
+	BackupFormatZip        = "zip"
+	BackupFormatRepository = "repository"
+)
+
+// Values of backup_retention.
+const (
+	BackupRetentionCount = "count"
+	BackupRetentionGFS   = "gfs"
+)
+
+// Policies for decisions that would otherwise ask the user.
+const (
+	PolicyPrompt     = "prompt"
+	PolicyDownload   = "download"
+	PolicyRedownload = "redownload"
+	PolicyUpdate     = "update"
+	PolicySkip       = "skip"
+	PolicyAbort      = "abort"
+	PolicyIgnore     = "ignore"
+)
+
 const (
 	maxSafeRAM            = 128
+	defaultServerType     = "paper"
 	defaultAutoRAMPercent = 50
 	defaultBackupCount    = 10
 	defaultBackupDir      = "backups"
 	defaultLogFile        = "launcher.log"
+	defaultPIDFile        = "launcher.pid"
+	defaultSocketFile     = "launcher.sock"
+	defaultVersionsDir    = "launcher-versions"
+	defaultVersionsKeep   = 5
+
+	defaultRestartDelay    = 5
+	defaultRestartMaxDelay = 300
+	defaultCrashLimit      = 5
+	defaultCrashWindow     = 10
+	defaultStopCommand     = "stop"
+	defaultStopTimeout     = 60
+	defaultStartupTimeout  = 300
 )
 
 func (c *Config) Validate() error {
+	if err := validatePolicy("server_type", c.ServerType, ServerTypes...); err != nil {
+		return err
+	}
 	if c.MinecraftVersion == "" {
 		return fmt.Errorf("minecraft_version cannot be empty")
 	}
+	if err := validatePolicy("build_channel", c.BuildChannel, download.BuildChannelDefault, download.BuildChannelStableOnly, download.BuildChannelExperimental); err != nil {
+		return err
+	}
+	if err := validatePolicy("launcher_update_channel", c.LauncherUpdateChannel, update.ChannelStable, update.ChannelBeta); err != nil {
+		return err
+	}
+	if c.PinnedBuild != "" && c.MinecraftVersion == "latest" {
+		return fmt.Errorf("pinned_build requires a specific minecraft_version")
+	}
+	if c.VersionsKeep < 0 {
+		return fmt.Errorf("versions_keep cannot be negative")
+	}
 	if c.MinRAM <= 0 {
 		return fmt.Errorf("min_ram must be greater than 0")
 	}
@@ -163,5 +372,53 @@ func (c *Config) Validate() error {
 	if c.BackupCount < 1 {
 		return fmt.Errorf("backup_count must be at least 1")
 	}
+	if c.BackupInterval < 0 {
+		return fmt.Errorf("backup_interval cannot be negative")
+	}
+	if err := validatePolicy("backup_format", c.BackupFormat, BackupFormatZip, BackupFormatRepository); err != nil {
+		return err
+	}
+	if err := validatePolicy("backup_retention", c.BackupRetention, BackupRetentionCount, BackupRetentionGFS); err != nil {
+		return err
+	}
+	if c.BackupMaxSize < 0 {
+		return fmt.Errorf("backup_max_size cannot be negative")
+	}
+	if err := validatePolicy("on_missing_jar", c.OnMissingJar, PolicyPrompt, PolicyDownload, PolicyAbort); err != nil {
+		return err
+	}
+	if err := validatePolicy("on_checksum_mismatch", c.OnChecksumMismatch, PolicyPrompt, PolicyRedownload, PolicyAbort, PolicyIgnore); err != nil {
+		return err
+	}
+	if err := validatePolicy("on_update", c.OnUpdate, PolicyPrompt, PolicyUpdate, PolicySkip); err != nil {
+		return err
+	}
+	if c.RestartDelay < 0 || c.RestartMaxDelay < 0 {
+		return fmt.Errorf("restart_delay and restart_max_delay cannot be negative")
+	}
+	if c.RestartMaxDelay != 0 && c.RestartDelay > c.RestartMaxDelay {
+		return fmt.Errorf("restart_delay cannot be greater than restart_max_delay")
+	}
+	if c.CrashLimit < 0 || c.CrashWindow < 0 {
+		return fmt.Errorf("crash_limit and crash_window cannot be negative")
+	}
+	if c.StopTimeout < 0 {
+		return fmt.Errorf("stop_timeout cannot be negative")
+	}
+	if c.StartupTimeout < 0 {
+		return fmt.Errorf("startup_timeout cannot be negative")
+	}
 	return nil
 }
+
+func validatePolicy(name, value string, allowed ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s", name, strings.Join(allowed, ", "))
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"path/filepath"
 	"testing"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
 )
 
 func TestLoad(t *testing.T) {
@@ -16,6 +19,12 @@ func TestLoad(t *testing.T) {
 	if cfg.MinRAM != 2 {
 		t.Errorf("expected MinRAM 2, got %d", cfg.MinRAM)
 	}
+	if cfg.ServerType != "paper" {
+		t.Errorf("expected server_type paper, got %s", cfg.ServerType)
+	}
+	if cfg.OnUpdate != PolicyUpdate {
+		t.Errorf("expected on_update to follow auto_update, got %s", cfg.OnUpdate)
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -54,6 +63,81 @@ func TestValidate(t *testing.T) {
 			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 5, BackupCount: 10},
 			true,
 		},
+		{
+			"invalid checksum policy",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, OnChecksumMismatch: "maybe"},
+			true,
+		},
+		{
+			"valid policies",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, OnMissingJar: PolicyAbort, OnChecksumMismatch: PolicyIgnore, OnUpdate: PolicySkip},
+			false,
+		},
+		{
+			"valid server type",
+			Config{ServerType: "purpur", MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10},
+			false,
+		},
+		{
+			"unknown server type",
+			Config{ServerType: "spigot", MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10},
+			true,
+		},
+		{
+			"unknown build channel",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BuildChannel: "nightly"},
+			true,
+		},
+		{
+			"pinned build with specific version",
+			Config{MinecraftVersion: "1.21.4", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BuildChannel: download.BuildChannelStableOnly, PinnedBuild: "120"},
+			false,
+		},
+		{
+			"pinned build with latest version",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, PinnedBuild: "120"},
+			true,
+		},
+		{
+			"beta launcher channel",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, LauncherUpdateChannel: update.ChannelBeta},
+			false,
+		},
+		{
+			"unknown launcher channel",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, LauncherUpdateChannel: "nightly"},
+			true,
+		},
+		{
+			"repository backup format",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BackupFormat: BackupFormatRepository},
+			false,
+		},
+		{
+			"unknown backup format",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BackupFormat: "tar"},
+			true,
+		},
+		{
+			"gfs backup retention",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BackupRetention: BackupRetentionGFS, BackupMaxSize: 10240},
+			false,
+		},
+		{
+			"unknown backup retention",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BackupRetention: "forever"},
+			true,
+		},
+		{
+			"negative backup max size",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, BackupMaxSize: -1},
+			true,
+		},
+		{
+			"negative versions keep",
+			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 85, BackupCount: 10, VersionsKeep: -1},
+			true,
+		},
 		{
 			"percentage too high",
 			Config{MinecraftVersion: "latest", MinRAM: 2, MaxRAM: 4, AutoRAMPercentage: 100, BackupCount: 10},
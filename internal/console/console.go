@@ -0,0 +1,202 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+const (
+	maxLineSize = 1024 * 1024
+
+	// ScrollbackLines is how many recent lines are replayed to attaching clients.
+	ScrollbackLines = 500
+)
+
+var ErrNotRunning = errors.New("server is not running")
+
+type subscriber struct {
+	re *regexp.Regexp
+	fn func(match []string)
+}
+
+// Console sits between the server process and everything that wants to read
+// from or write to it: the terminal, launcher subsystems and attached clients.
+// It outlives individual server processes so subscriptions survive restarts.
+type Console struct {
+	mu      sync.Mutex
+	outputs map[int]io.Writer
+	subs    map[int]subscriber
+	nextID  int
+	stdin   io.Writer
+	history []string
+}
+
+func New(out io.Writer) *Console {
+	c := &Console{
+		outputs: make(map[int]io.Writer),
+		subs:    make(map[int]subscriber),
+	}
+	if out != nil {
+		c.AddOutput(out)
+	}
+	return c
+}
+
+// AddOutput mirrors every server line to w until the returned func is called.
+func (c *Console) AddOutput(w io.Writer) (remove func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addOutputLocked(w)
+}
+
+// AttachOutput returns the recent scrollback and mirrors every later line
+// to w, with no line lost or repeated in between. w must not block.
+func (c *Console) AttachOutput(w io.Writer) (scrollback []string, remove func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scrollback = append([]string(nil), c.history...)
+	return scrollback, c.addOutputLocked(w)
+}
+
+func (c *Console) addOutputLocked(w io.Writer) (remove func()) {
+	id := c.nextID
+	c.nextID++
+	c.outputs[id] = w
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.outputs, id)
+	}
+}
+
+// Subscribe calls fn with the submatches of every server line matching re.
+// fn runs on the output goroutine and must not block.
+func (c *Console) Subscribe(re *regexp.Regexp, fn func(match []string)) (unsubscribe func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.subs[id] = subscriber{re: re, fn: fn}
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+// WaitFor blocks until a server line matches re or ctx is done.
+func (c *Console) WaitFor(ctx context.Context, re *regexp.Regexp) ([]string, error) {
+	matched := make(chan []string, 1)
+	unsubscribe := c.Subscribe(re, func(match []string) {
+		select {
+		case matched <- match:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	select {
+	case match := <-matched:
+		return match, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Bind connects the console to a server's stdin until the returned func is called.
+func (c *Console) Bind(stdin io.Writer) (unbind func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stdin = stdin
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.stdin == stdin {
+			c.stdin = nil
+		}
+	}
+}
+
+// Running reports whether a server process is bound to the console.
+func (c *Console) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stdin != nil
+}
+
+// Send writes a command to the server console.
+func (c *Console) Send(command string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stdin == nil {
+		return ErrNotRunning
+	}
+	command = strings.TrimRight(command, "\r\n")
+	if _, err := io.WriteString(c.stdin, command+"\n"); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	return nil
+}
+
+// Pump reads server output from r until EOF, mirroring each line to the
+// outputs and dispatching it to subscribers.
+func (c *Console) Pump(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		c.dispatch(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func (c *Console) dispatch(line string) {
+	c.mu.Lock()
+	if len(c.history) >= ScrollbackLines {
+		c.history = append(c.history[:0], c.history[1:]...)
+	}
+	c.history = append(c.history, line)
+	for _, w := range c.outputs {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			logger.Debug("Failed to write console output: %v", err)
+		}
+	}
+	matched := make([]func(), 0, len(c.subs))
+	for _, sub := range c.subs {
+		if match := sub.re.FindStringSubmatch(line); match != nil {
+			fn := sub.fn
+			matched = append(matched, func() { fn(match) })
+		}
+	}
+	c.mu.Unlock()
+
+	for _, fn := range matched {
+		fn()
+	}
+}
+
+// ForwardInput sends each line read from r to the server. It should be
+// started once per launcher, since reads from a terminal cannot be cancelled.
+func (c *Console) ForwardInput(r io.Reader) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			if sendErr := c.Send(line); errors.Is(sendErr, ErrNotRunning) {
+				logger.Warn("Server is not running, ignoring input: %s", line)
+			} else if sendErr != nil {
+				logger.Warn("%v", sendErr)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPumpMirrorsAndDispatches(t *testing.T) {
+	var out bytes.Buffer
+	c := New(&out)
+
+	var joined []string
+	unsubscribe := c.Subscribe(regexp.MustCompile(`(\w+) joined the game`), func(match []string) {
+		joined = append(joined, match[1])
+	})
+
+	input := "[12:00:00 INFO]: Steve joined the game\n[12:00:01 INFO]: Alex joined the game\n"
+	if err := c.Pump(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != input {
+		t.Errorf("output not mirrored, got %q", out.String())
+	}
+	if strings.Join(joined, ",") != "Steve,Alex" {
+		t.Errorf("unexpected matches: %v", joined)
+	}
+
+	unsubscribe()
+	if err := c.Pump(strings.NewReader("[12:00:02 INFO]: Herobrine joined the game\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(joined) != 2 {
+		t.Errorf("expected no matches after unsubscribe, got %v", joined)
+	}
+}
+
+func TestSend(t *testing.T) {
+	c := New(nil)
+	if err := c.Send("list"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+
+	var stdin bytes.Buffer
+	unbind := c.Bind(&stdin)
+	if err := c.Send("say hello\n"); err != nil {
+		t.Fatal(err)
+	}
+	if stdin.String() != "say hello\n" {
+		t.Errorf("unexpected stdin: %q", stdin.String())
+	}
+
+	unbind()
+	if c.Running() {
+		t.Error("expected console to be unbound")
+	}
+}
+
+func TestForwardInput(t *testing.T) {
+	c := New(nil)
+	var stdin bytes.Buffer
+	c.Bind(&stdin)
+
+	c.ForwardInput(strings.NewReader("list\r\n\nstop"))
+	if stdin.String() != "list\nstop\n" {
+		t.Errorf("unexpected stdin: %q", stdin.String())
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	c := New(nil)
+	re := regexp.MustCompile(`Done \(([\d.]+)s\)!`)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Pump(strings.NewReader("[12:00:00 INFO]: Done (3.21s)! For help, type \"help\"\n"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	match, err := c.WaitFor(ctx, re)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match[1] != "3.21" {
+		t.Errorf("expected 3.21, got %s", match[1])
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitFor(ctx, re); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestAttachOutputReplaysScrollback(t *testing.T) {
+	c := New(nil)
+	for i := 0; i < ScrollbackLines+5; i++ {
+		c.dispatch("line")
+	}
+	c.dispatch("last")
+
+	var live bytes.Buffer
+	scrollback, remove := c.AttachOutput(&live)
+	if len(scrollback) != ScrollbackLines {
+		t.Errorf("expected %d scrollback lines, got %d", ScrollbackLines, len(scrollback))
+	}
+	if scrollback[len(scrollback)-1] != "last" {
+		t.Errorf("expected newest line last, got %q", scrollback[len(scrollback)-1])
+	}
+
+	c.dispatch("live")
+	remove()
+	c.dispatch("after")
+	if live.String() != "live\n" {
+		t.Errorf("unexpected live output: %q", live.String())
+	}
+}
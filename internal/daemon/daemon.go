@@ -0,0 +1,268 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+const (
+	requestAttach = "ATTACH"
+	requestStop   = "STOP"
+	responseOK    = "OK"
+
+	requestTimeout = 5 * time.Second
+	clientBacklog  = 1024
+)
+
+var ErrNotRunning = errors.New("launcher is not running")
+
+// Server exposes a launcher's console on a Unix domain socket so that other
+// launcher invocations can attach to it or ask it to stop.
+type Server struct {
+	listener net.Listener
+	con      *console.Console
+	stop     func()
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// Listen creates the control socket at path. stop is called when a client
+// requests a shutdown and should trigger the graceful stop path.
+func Listen(path string, con *console.Console, stop func()) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("control socket %s is in use by another launcher", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control socket: %w", err)
+	}
+
+	s := &Server{listener: l, con: con, stop: stop, conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting clients, disconnects attached ones and removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	if err := conn.SetReadDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return
+	}
+	request, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	switch strings.TrimSpace(request) {
+	case requestStop:
+		logger.Info("Stop requested over control socket")
+		fmt.Fprintln(conn, responseOK)
+		s.stop()
+	case requestAttach:
+		s.attach(conn, r)
+	default:
+		fmt.Fprintf(conn, "ERR unknown request %q\n", strings.TrimSpace(request))
+	}
+}
+
+// attach replays the scrollback, then streams server output to the client
+// and forwards each line the client sends to the server console.
+func (s *Server) attach(conn net.Conn, r *bufio.Reader) {
+	logger.Info("Console client attached")
+	defer logger.Info("Console client detached")
+
+	out := newQueueWriter(clientBacklog)
+	scrollback, remove := s.con.AttachOutput(out)
+	defer remove()
+	defer out.close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := bufio.NewWriter(conn)
+		for _, line := range scrollback {
+			w.WriteString(line + "\n")
+		}
+		if err := w.Flush(); err != nil {
+			conn.Close()
+			return
+		}
+		for line := range out.lines {
+			if _, err := io.WriteString(conn, line); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		line, err := r.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			if sendErr := s.con.Send(line); sendErr != nil {
+				out.Write([]byte(fmt.Sprintf("[launcher] %v\n", sendErr)))
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	out.close()
+	<-done
+}
+
+// queueWriter hands lines to a client goroutine without ever blocking the
+// console; lines are dropped if a slow client falls too far behind.
+type queueWriter struct {
+	mu     sync.Mutex
+	lines  chan string
+	closed bool
+}
+
+func newQueueWriter(size int) *queueWriter {
+	return &queueWriter{lines: make(chan string, size)}
+}
+
+func (q *queueWriter) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, io.ErrClosedPipe
+	}
+	select {
+	case q.lines <- string(p):
+	default:
+	}
+	return len(p), nil
+}
+
+func (q *queueWriter) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.lines)
+	}
+}
+
+func dial(path string) (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", path, requestTimeout)
+	if err != nil {
+		if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+			return nil, ErrNotRunning
+		}
+		return nil, fmt.Errorf("%w (%v)", ErrNotRunning, err)
+	}
+	return conn, nil
+}
+
+// Stop asks the launcher listening on path to shut down gracefully.
+func Stop(path string) error {
+	conn, err := dial(path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(conn, requestStop); err != nil {
+		return fmt.Errorf("failed to send stop request: %w", err)
+	}
+	resp, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read stop response: %w", err)
+	}
+	if resp = strings.TrimSpace(resp); resp != responseOK {
+		return fmt.Errorf("launcher rejected stop request: %s", resp)
+	}
+	return nil
+}
+
+// Attach connects to the launcher's console, copying its output to out and
+// lines read from in to the server, until ctx is cancelled or the launcher exits.
+func Attach(ctx context.Context, path string, in io.Reader, out io.Writer) error {
+	conn, err := dial(path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, requestAttach); err != nil {
+		return fmt.Errorf("failed to attach: %w", err)
+	}
+
+	go func() {
+		io.Copy(conn, in)
+	}()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	_, err = io.Copy(out, conn)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
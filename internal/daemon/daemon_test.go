@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "launcher.sock")
+	stopped := make(chan struct{})
+	s, err := Listen(path, console.New(nil), func() { close(stopped) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := Stop(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("stop callback was not called")
+	}
+}
+
+func TestStopNotRunning(t *testing.T) {
+	if err := Stop(filepath.Join(t.TempDir(), "launcher.sock")); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "launcher.sock")
+	con := console.New(nil)
+	var stdin syncBuffer
+	con.Bind(&stdin)
+	con.Pump(strings.NewReader("old line\n"))
+
+	s, err := Listen(path, con, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	inR, inW := io.Pipe()
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- Attach(ctx, path, inR, &out) }()
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), "old line") })
+
+	con.Pump(strings.NewReader("new line\n"))
+	waitFor(t, func() bool { return strings.Contains(out.String(), "new line") })
+
+	io.WriteString(inW, "say hi\n")
+	waitFor(t, func() bool { return stdin.String() == "say hi\n" })
+
+	cancel()
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestListenRefusesLiveSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "launcher.sock")
+	s, err := Listen(path, console.New(nil), func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := Listen(path, console.New(nil), func() {}); err == nil {
+		t.Error("expected error for socket in use")
+	}
+}
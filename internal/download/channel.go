@@ -0,0 +1,75 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of the build_channel setting.
+const (
+	BuildChannelDefault      = "default"
+	BuildChannelExperimental = "experimental"
+	BuildChannelStableOnly   = "stable-only"
+)
+
+// BuildPolicy decides which build of a version is installed.
+type BuildPolicy struct {
+	// Channel is one of the BuildChannel values. With the default channel,
+	// experimental builds are only used while a version has no stable build.
+	Channel string
+	// Pinned locks the server to one build ID when set.
+	Pinned string
+}
+
+var buildPolicy = BuildPolicy{Channel: BuildChannelDefault}
+
+func SetBuildPolicy(policy BuildPolicy) {
+	if policy.Channel == "" {
+		policy.Channel = BuildChannelDefault
+	}
+	buildPolicy = policy
+}
+
+func isStable(channel string) bool {
+	return channel == ChannelStable || channel == ChannelRecommended
+}
+
+// selectBuild picks the build to install from a newest-first list. The
+// reason explains why a newer build was passed over, if one was.
+func selectBuild(builds []Build, policy BuildPolicy) (build *Build, reason string, err error) {
+	if len(builds) == 0 {
+		return nil, "", fmt.Errorf("no builds found")
+	}
+	newest := &builds[0]
+
+	if policy.Pinned != "" {
+		for i := range builds {
+			if builds[i].ID != policy.Pinned {
+				continue
+			}
+			if i > 0 {
+				reason = fmt.Sprintf("pinned to build %s (newest is %s)", policy.Pinned, newest.ID)
+			}
+			return &builds[i], reason, nil
+		}
+		return nil, "", fmt.Errorf("pinned build %s not found", policy.Pinned)
+	}
+
+	if policy.Channel == BuildChannelExperimental {
+		return newest, "", nil
+	}
+	for i := range builds {
+		if !isStable(builds[i].Channel) {
+			continue
+		}
+		if i > 0 {
+			reason = fmt.Sprintf("newer build %s is on the %s channel (build_channel: %s)",
+				newest.ID, strings.ToLower(newest.Channel), policy.Channel)
+		}
+		return &builds[i], reason, nil
+	}
+	if policy.Channel == BuildChannelStableOnly {
+		return nil, "", fmt.Errorf("no stable builds yet (newest is %s on the %s channel)", newest.ID, strings.ToLower(newest.Channel))
+	}
+	return newest, "", nil
+}
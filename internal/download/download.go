@@ -2,175 +2,352 @@ package download
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
-	"strconv"
+	"strings"
 
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/cache"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
 )
 
-const (
-	apiBase = "https://api.papermc.io/v2/projects/paper"
+var (
+	provider Provider = NewFillClient(fillBase, TypePaper)
+	jarCache *cache.Cache
 )
 
-type ProjectResponse struct {
-	Versions []string `json:"versions"`
+// SetServerType selects the server software that is downloaded and updated.
+func SetServerType(serverType string) error {
+	p, err := NewProvider(serverType)
+	if err != nil {
+		return err
+	}
+	provider = p
+	return nil
 }
 
-type BuildsResponse struct {
-	Builds []struct {
-		Build int `json:"build"`
-	} `json:"builds"`
+// SetCache shares downloaded JARs through c. A nil cache turns sharing off.
+func SetCache(c *cache.Cache) {
+	jarCache = c
 }
 
-type DownloadResponse struct {
-	Downloads struct {
-		Application struct {
-			Name string `json:"name"`
-		} `json:"application"`
-	} `json:"downloads"`
+// Update describes the build the build policy selects for the version of
+// an installed JAR.
+type Update struct {
+	Available    bool
+	CurrentBuild string
+	Version      *Version
+	// Latest is the selected build, which is older than the installed one
+	// when the server is pinned to an earlier build.
+	Latest *Build
+	// Changes lists the builds between the installed and the selected one,
+	// newest first.
+	Changes []Build
+	// SkipReason explains why a newer build than Latest was not selected.
+	SkipReason string
 }
 
-var (
-	jarNameRegex = regexp.MustCompile(`paper-(.+)-(\d+)\.jar`)
-)
-
-func CheckUpdate(ctx context.Context, jarName string) (bool, int, string, error) {
-	matches := jarNameRegex.FindStringSubmatch(jarName)
-	if len(matches) != 3 {
-		return false, 0, "", fmt.Errorf("invalid jar filename format: %s", jarName)
+func CheckUpdate(ctx context.Context, jarName string) (*Update, error) {
+	version, currentBuild, ok := provider.ParseJarName(jarName)
+	if !ok {
+		return nil, fmt.Errorf("invalid jar filename format: %s", jarName)
 	}
 
-	version := matches[1]
-	currentBuild, err := strconv.Atoi(matches[2])
+	ver, err := provider.Version(ctx, version)
 	if err != nil {
-		return false, 0, "", fmt.Errorf("invalid build number: %s", matches[2])
+		return nil, err
 	}
-
-	latestBuild, err := getLatestBuild(ctx, apiBase, version)
+	builds, err := provider.Builds(ctx, version)
 	if err != nil {
-		return false, 0, "", fmt.Errorf("failed to get latest build: %w", err)
+		return nil, fmt.Errorf("failed to get latest build: %w", err)
+	}
+	saveMetadata(ver.ID, metadata{Version: ver, Builds: builds})
+	target, reason, err := selectBuild(builds, buildPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select build for %s: %w", version, err)
 	}
 
-	if latestBuild > currentBuild {
-		newJarName, err := getJarName(ctx, apiBase, version, latestBuild)
-		if err != nil {
-			return true, latestBuild, "", fmt.Errorf("failed to get new jar name: %w", err)
+	u := &Update{
+		CurrentBuild: currentBuild,
+		Version:      ver,
+		Latest:       target,
+		SkipReason:   reason,
+	}
+	newer, installed := false, false
+	for _, b := range builds {
+		if b.ID == currentBuild {
+			installed = true
+			break
+		}
+		if b.ID == target.ID {
+			newer = true
+		}
+		if newer {
+			u.Changes = append(u.Changes, b)
 		}
-		return true, latestBuild, newJarName, nil
 	}
-
-	return false, 0, "", nil
+	// An installed build that is newer than the selected one, or missing
+	// from the list, is only replaced when the server is pinned.
+	if buildPolicy.Pinned != "" {
+		u.Available = target.ID != currentBuild
+	} else {
+		u.Available = newer && installed
+	}
+	if !installed {
+		u.Changes = nil
+	}
+	return u, nil
 }
 
 func DownloadJar(ctx context.Context, version string) (string, error) {
-	if version == "latest" {
-		ver, err := getLatestVersion(ctx, apiBase)
+	if utils.Offline() {
+		return installOffline(ctx, version)
+	}
+	requested := version
+	if version == latestVersion {
+		latest, err := provider.LatestVersion(ctx)
 		if err != nil {
 			return "", err
 		}
-		version = ver
+		version = latest
 	}
 
-	build, err := getLatestBuild(ctx, apiBase, version)
+	ver, err := provider.Version(ctx, version)
 	if err != nil {
 		return "", err
 	}
-
-	jarName, err := getJarName(ctx, apiBase, version, build)
+	builds, err := provider.Builds(ctx, ver.ID)
 	if err != nil {
 		return "", err
 	}
-
-	if _, err := os.Stat(jarName); err == nil {
-		logger.Info("JAR file already exists: %s", jarName)
-		checksumFile := jarName + ".sha256"
-		if expectedChecksum, err := utils.LoadChecksumFile(checksumFile); err == nil && expectedChecksum != "" {
-			if err := utils.ValidateChecksum(jarName, expectedChecksum); err == nil {
-				logger.Info("Existing JAR file checksum validated")
-				return jarName, nil
-			}
-			logger.Info("Checksum validation failed, re-downloading...")
-		} else {
-			logger.Info("No checksum file found, re-downloading to ensure integrity...")
-		}
+	saveMetadata(ver.ID, metadata{Version: ver, Builds: builds})
+	if requested == latestVersion {
+		saveMetadata(latestVersion, metadata{Version: ver})
+	}
+	build, reason, err := selectBuild(builds, buildPolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to select build for %s: %w", ver.ID, err)
 	}
 
-	url := fmt.Sprintf("%s/versions/%s/builds/%d/downloads/%s", apiBase, version, build, jarName)
-	logger.Info("Downloading %s...", jarName)
+	logSelection(ver, build, reason)
+	return installBuild(ctx, build)
+}
 
-	if err := utils.DownloadFile(ctx, url, jarName); err != nil {
-		return "", err
+func logSelection(ver *Version, build *Build, reason string) {
+	logger.Info("%s (%s channel)", DescribeBuild(ver.ID, build), strings.ToLower(build.Channel))
+	if reason != "" {
+		logger.Info("Not using the newest build: %s", reason)
 	}
+	if !isStable(build.Channel) && buildPolicy.Channel != BuildChannelExperimental {
+		logger.Warn("%s %s has no stable build yet, using an experimental one", provider.Name(), ver.ID)
+	}
+	if ver.Support != "" && ver.Support != SupportSupported {
+		logger.Warn("Minecraft %s is %s by %s", ver.ID, strings.ToLower(ver.Support), provider.Name())
+	}
+	if ver.MinJava > 0 {
+		logger.Info("Requires Java %d or newer", ver.MinJava)
+	}
+}
 
-	checksum, err := utils.ValidateJarAndCalculateChecksum(jarName)
+// installOffline installs the newest build in the download cache that the
+// build policy allows, choosing from the builds saved when the version was
+// last fetched. Without saved builds it falls back to the most recently
+// used JAR of the version.
+func installOffline(ctx context.Context, version string) (string, error) {
+	if jarCache == nil {
+		return "", fmt.Errorf("%w and the download cache is disabled", utils.ErrOffline)
+	}
+	if version == latestVersion {
+		if latest, err := loadMetadata(latestVersion); err == nil {
+			version = latest.Version.ID
+		}
+	}
+	m, err := loadMetadata(version)
 	if err != nil {
-		return "", fmt.Errorf("JAR validation failed: %w", err)
+		logger.Debug("%v", err)
+		return installCached(version)
 	}
 
-	checksumFile := jarName + ".sha256"
-	if err := utils.SaveChecksumFile(checksumFile, checksum); err != nil {
-		return "", fmt.Errorf("failed to save checksum file: %w", err)
+	entries, err := jarCache.List()
+	if err != nil {
+		return "", err
+	}
+	var cached []Build
+	for _, b := range m.Builds {
+		if isCached(b.Download, entries) {
+			cached = append(cached, b)
+		}
+	}
+	build, reason, err := selectBuild(cached, buildPolicy)
+	if err != nil {
+		return "", fmt.Errorf("%w: no usable %s %s build in the download cache: %v", utils.ErrOffline, provider.Name(), m.Version.ID, err)
 	}
 
-	logger.Info("Downloaded and validated JAR file (SHA-256: %s)", checksum[:16]+"...")
-	return jarName, nil
+	logger.Info("Offline mode: choosing from the builds saved on %s", m.Saved.Format("2006-01-02 15:04"))
+	logSelection(m.Version, build, reason)
+	return installBuild(ctx, build)
 }
 
-func getLatestVersion(ctx context.Context, baseURL string) (string, error) {
-	resp, err := utils.DoRequest(ctx,baseURL)
+// isCached reports whether installFromCache would look for the download
+// among entries and find it.
+func isCached(dl Download, entries []cache.Entry) bool {
+	for _, e := range entries {
+		if dl.Checksum.Algorithm == "sha256" {
+			if strings.EqualFold(e.SHA256, dl.Checksum.Value) {
+				return true
+			}
+		} else if e.Name == dl.Name && e.URL == dl.URL {
+			return true
+		}
+	}
+	return false
+}
+
+// installCached installs the most recently used JAR of the configured server
+// type and version from the download cache, without contacting its source.
+func installCached(version string) (string, error) {
+	if jarCache == nil {
+		return "", fmt.Errorf("%w and the download cache is disabled", utils.ErrOffline)
+	}
+	entries, err := jarCache.List()
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch versions: %w", err)
+		return "", err
+	}
+	for _, e := range entries {
+		v, _, ok := provider.ParseJarName(e.Name)
+		if !ok || (version != latestVersion && v != version) {
+			continue
+		}
+		if ok, err := jarCache.Install(e.SHA256, e.Name); !ok {
+			if err != nil {
+				logger.Warn("%v", err)
+			}
+			continue
+		}
+		if err := utils.SaveChecksumFile(e.Name+".sha256", e.SHA256); err != nil {
+			return "", fmt.Errorf("failed to save checksum file: %w", err)
+		}
+		logger.Info("Offline mode: installed %s from the download cache", e.Name)
+		return e.Name, nil
 	}
-	defer resp.Body.Close()
+	return "", fmt.Errorf("%w: no %s %s JAR in the download cache", utils.ErrOffline, provider.Name(), version)
+}
 
-	var proj ProjectResponse
-	if err := json.NewDecoder(resp.Body).Decode(&proj); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+// installFromCache places the cached copy of a build in the current
+// directory. Builds with a published SHA-256 are looked up by it. Other
+// builds are looked up by name and download URL, and the cached JAR must
+// still match the MD5 or SHA-1 their source publishes.
+func installFromCache(dl Download) (bool, error) {
+	if dl.Checksum.Algorithm == "sha256" {
+		return jarCache.Install(dl.Checksum.Value, dl.Name)
+	}
+	entries, err := jarCache.List()
+	if err != nil {
+		return false, err
 	}
+	for _, e := range entries {
+		if e.Name != dl.Name || e.URL != dl.URL {
+			continue
+		}
+		if ok, err := jarCache.Install(e.SHA256, dl.Name); !ok {
+			if err != nil {
+				logger.Warn("%v", err)
+			}
+			continue
+		}
+		if err := dl.Checksum.Verify(dl.Name); err != nil {
+			os.Remove(dl.Name)
+			jarCache.Remove(e.SHA256)
+			logger.Warn("Dropped stale cache entry: %v", err)
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
 
-	if len(proj.Versions) == 0 {
-		return "", fmt.Errorf("no versions found")
+// DescribeBuild names a build for log messages, e.g. "paper 1.21.4 build 120".
+func DescribeBuild(version string, build *Build) string {
+	if build.ID == "" {
+		return fmt.Sprintf("%s %s", provider.Name(), version)
 	}
+	return fmt.Sprintf("%s %s build %s", provider.Name(), version, build.ID)
+}
 
-	return proj.Versions[len(proj.Versions)-1], nil
+// ParseJarName extracts the version and build from a JAR name of the
+// configured server type.
+func ParseJarName(name string) (version, build string, ok bool) {
+	return provider.ParseJarName(name)
 }
 
-func getLatestBuild(ctx context.Context, baseURL, version string) (int, error) {
-	url := fmt.Sprintf("%s/versions/%s/builds", baseURL, version)
-	resp, err := utils.DoRequest(ctx,url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch builds: %w", err)
+func installBuild(ctx context.Context, build *Build) (string, error) {
+	jarName := build.Download.Name
+	published := build.Download.Checksum
+	if published.Value == "" {
+		logger.Warn("No checksum is published for %s, it will only be checked locally", jarName)
 	}
-	defer resp.Body.Close()
 
-	var builds BuildsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&builds); err != nil {
-		return 0, fmt.Errorf("failed to parse response: %w", err)
+	if _, err := os.Stat(jarName); err == nil {
+		logger.Info("JAR file already exists: %s", jarName)
+		var expectedChecksum string
+		if published.Algorithm == "sha256" {
+			expectedChecksum = published.Value
+		} else {
+			expectedChecksum, _ = utils.LoadChecksumFile(jarName + ".sha256")
+		}
+		if expectedChecksum != "" {
+			if err := utils.ValidateChecksum(jarName, expectedChecksum); err == nil {
+				logger.Info("Existing JAR file checksum validated")
+				if err := utils.SaveChecksumFile(jarName+".sha256", strings.ToLower(expectedChecksum)); err != nil {
+					logger.Warn("Failed to save checksum file: %v", err)
+				}
+				return jarName, nil
+			}
+			logger.Info("Checksum validation failed, re-downloading...")
+		} else {
+			logger.Info("No checksum file found, re-downloading to ensure integrity...")
+		}
 	}
 
-	if len(builds.Builds) == 0 {
-		return 0, fmt.Errorf("no builds found")
+	cached := false
+	if jarCache != nil {
+		var err error
+		if cached, err = installFromCache(build.Download); err != nil {
+			logger.Warn("Failed to use download cache: %v", err)
+		}
 	}
 
-	return builds.Builds[len(builds.Builds)-1].Build, nil
-}
+	if cached {
+		logger.Info("Using %s from the download cache", jarName)
+	} else {
+		logger.Info("Downloading %s...", jarName)
+		if err := utils.DownloadFile(ctx, build.Download.URL, jarName, published); err != nil {
+			return "", err
+		}
+	}
 
-func getJarName(ctx context.Context, baseURL, version string, build int) (string, error) {
-	url := fmt.Sprintf("%s/versions/%s/builds/%d", baseURL, version, build)
-	resp, err := utils.DoRequest(ctx,url)
+	checksum, err := utils.ValidateJarAndCalculateChecksum(jarName)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch download info: %w", err)
+		return "", fmt.Errorf("JAR validation failed: %w", err)
+	}
+
+	checksumFile := jarName + ".sha256"
+	if err := utils.SaveChecksumFile(checksumFile, checksum); err != nil {
+		return "", fmt.Errorf("failed to save checksum file: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var download DownloadResponse
-	if err := json.NewDecoder(resp.Body).Decode(&download); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	if jarCache != nil && !cached {
+		if err := jarCache.Add(jarName, checksum, build.Download.URL); err != nil {
+			logger.Warn("Failed to add %s to the download cache: %v", jarName, err)
+		}
 	}
 
-	return download.Downloads.Application.Name, nil
+	if cached {
+		logger.Info("Installed cached JAR (SHA-256: %s)", checksum[:16]+"...")
+	} else if published.Value != "" {
+		logger.Info("Downloaded JAR matches the published %s checksum (SHA-256: %s)", strings.ToUpper(published.Algorithm), checksum[:16]+"...")
+	} else {
+		logger.Info("Downloaded and validated JAR file (SHA-256: %s)", checksum[:16]+"...")
+	}
+	return jarName, nil
 }
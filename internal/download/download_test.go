@@ -1,13 +1,22 @@
 package download
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/cache"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
 )
 
@@ -18,67 +27,698 @@ func withMockClient(client *http.Client, fn func()) {
 	fn()
 }
 
-func TestGetLatestVersion(t *testing.T) {
+// fillServer is an httptest stand-in for the Fill API serving Paper 1.21.4
+// builds 10 and 20, whose JAR is jar.
+func fillServer(t *testing.T, jar []byte, publishedSHA string) *httptest.Server {
+	t.Helper()
+	var ts *httptest.Server
+	build := func(id int, channel string) string {
+		return fmt.Sprintf(`{"id": %d, "time": "2025-01-0%dT00:00:00Z", "channel": %q,
+			"commits": [{"sha": "abc%d", "message": "Fix thing %d\n\nDetails"}],
+			"downloads": {"server:default": {"name": "paper-1.21.4-%d.jar", "checksums": {"sha256": %q}, "size": %d, "url": "%s/data/paper-1.21.4-%d.jar"}}}`,
+			id, id/10, channel, id, id, id, publishedSHA, len(jar), ts.URL, id)
+	}
+	version := `{"version": {"id": "1.21.4", "support": {"status": "SUPPORTED"}, "java": {"version": {"minimum": 21}}}, "builds": [20, 10]}`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects/paper/versions", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"versions": [{"version": {"id": "1.21.5-pre1"}, "builds": []}, %s]}`, version)
+	})
+	mux.HandleFunc("/projects/paper/versions/1.21.4", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, version)
+	})
+	mux.HandleFunc("/projects/paper/versions/1.21.4/builds", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s, %s]", build(10, "STABLE"), build(20, "STABLE"))
+	})
+	mux.HandleFunc("/projects/paper/versions/1.21.4/builds/20", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, build(20, "STABLE"))
+	})
+	mux.HandleFunc("/data/paper-1.21.4-20.jar", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jar)
+	})
+	ts = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func withFillServer(t *testing.T, ts *httptest.Server) {
+	t.Helper()
+	withProvider(t, NewFillClient(ts.URL, "paper"), ts)
+}
+
+func withProvider(t *testing.T, p Provider, ts *httptest.Server) {
+	t.Helper()
+	oldProvider, oldClient := provider, utils.HTTPClient
+	provider = p
+	utils.HTTPClient = ts.Client()
+	t.Cleanup(func() { provider, utils.HTTPClient = oldProvider, oldClient })
+}
+
+func testJar(t *testing.T) ([]byte, string) {
+	t.Helper()
+	return testJarWith(t, "Manifest-Version: 1.0\n")
+}
+
+// testJarWith returns a JAR with the given manifest and its SHA-256.
+func testJarWith(t *testing.T, manifest string) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(manifest))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestFillClient(t *testing.T) {
+	jar, sha := testJar(t)
+	ts := fillServer(t, jar, sha)
+	c := NewFillClient(ts.URL, "paper")
+
+	withMockClient(ts.Client(), func() {
+		versions, err := c.Versions(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(versions) != 2 || versions[1].ID != "1.21.4" {
+			t.Fatalf("unexpected versions: %+v", versions)
+		}
+
+		latest, err := c.LatestVersion(context.Background())
+		if err != nil || latest != "1.21.4" {
+			t.Errorf("expected latest version with builds 1.21.4, got %s (%v)", latest, err)
+		}
+
+		ver, err := c.Version(context.Background(), "1.21.4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ver.Support != SupportSupported || ver.MinJava != 21 || len(ver.Builds) != 2 {
+			t.Errorf("unexpected version: %+v", ver)
+		}
+
+		builds, err := c.Builds(context.Background(), "1.21.4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(builds) != 2 || builds[0].ID != "20" {
+			t.Fatalf("expected builds newest first, got %+v", builds)
+		}
+		b := builds[0]
+		if b.Channel != ChannelStable || b.Download.Name != "paper-1.21.4-20.jar" || b.Download.Checksum.Value != sha {
+			t.Errorf("unexpected build: %+v", b)
+		}
+		if len(b.Commits) != 1 || b.Commits[0].Message != "Fix thing 20\n\nDetails" {
+			t.Errorf("unexpected commits: %+v", b.Commits)
+		}
+
+		build, err := c.Build(context.Background(), "1.21.4", 20)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if build.Download.URL != ts.URL+"/data/paper-1.21.4-20.jar" {
+			t.Errorf("unexpected download URL %s", build.Download.URL)
+		}
+	})
+}
+
+func TestCheckUpdate(t *testing.T) {
+	jar, sha := testJar(t)
+	withFillServer(t, fillServer(t, jar, sha))
+
+	tests := []struct {
+		jar       string
+		available bool
+		changes   int
+	}{
+		{"paper-1.21.4-5.jar", false, 0}, // not in the build list
+		{"paper-1.21.4-10.jar", true, 1},
+		{"paper-1.21.4-20.jar", false, 0},
+	}
+	for _, tt := range tests {
+		u, err := CheckUpdate(context.Background(), tt.jar)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.jar, err)
+		}
+		if u.Available != tt.available || len(u.Changes) != tt.changes {
+			t.Errorf("%s: expected available=%v changes=%d, got %v %d", tt.jar, tt.available, tt.changes, u.Available, len(u.Changes))
+		}
+		if u.Latest.ID != "20" || u.Version.MinJava != 21 {
+			t.Errorf("%s: unexpected update info %+v", tt.jar, u)
+		}
+	}
+
+	if _, err := CheckUpdate(context.Background(), "server.jar"); err == nil {
+		t.Error("expected error for unrecognised jar name")
+	}
+
+	SetBuildPolicy(BuildPolicy{Pinned: "10"})
+	defer SetBuildPolicy(BuildPolicy{})
+	u, err := CheckUpdate(context.Background(), "paper-1.21.4-10.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Available || u.SkipReason != "pinned to build 10 (newest is 20)" {
+		t.Errorf("expected pinned build to block the update, got available=%v reason=%q", u.Available, u.SkipReason)
+	}
+}
+
+// buildsProvider serves a fixed newest-first build list for Paper 1.21.4.
+type buildsProvider struct {
+	builds []Build
+}
+
+func (p buildsProvider) Name() string { return TypePaper }
+
+func (p buildsProvider) LatestVersion(context.Context) (string, error) { return "1.21.4", nil }
+
+func (p buildsProvider) Version(context.Context, string) (*Version, error) {
+	return &Version{ID: "1.21.4", MinJava: 21}, nil
+}
+
+func (p buildsProvider) Builds(context.Context, string) ([]Build, error) { return p.builds, nil }
+
+func (p buildsProvider) ParseJarName(name string) (string, string, bool) {
+	return parseBuildJarName(TypePaper, name)
+}
+
+func TestCheckUpdateDowngrade(t *testing.T) {
+	oldProvider := provider
+	provider = buildsProvider{builds: []Build{
+		{ID: "30", Channel: ChannelBeta},
+		{ID: "20", Channel: ChannelStable},
+		{ID: "10", Channel: ChannelStable},
+	}}
+	t.Cleanup(func() { provider = oldProvider })
+
+	tests := []struct {
+		name      string
+		jar       string
+		policy    BuildPolicy
+		available bool
+	}{
+		{"newer experimental build installed", "paper-1.21.4-30.jar", BuildPolicy{}, false},
+		{"newer experimental build with stable-only", "paper-1.21.4-30.jar", BuildPolicy{Channel: BuildChannelStableOnly}, false},
+		{"build missing from the list", "paper-1.21.4-40.jar", BuildPolicy{}, false},
+		{"older stable build installed", "paper-1.21.4-10.jar", BuildPolicy{}, true},
+		{"pinned to an older build", "paper-1.21.4-30.jar", BuildPolicy{Pinned: "10"}, true},
+		{"pinned build missing from the list", "paper-1.21.4-40.jar", BuildPolicy{Pinned: "20"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetBuildPolicy(tt.policy)
+			defer SetBuildPolicy(BuildPolicy{})
+			u, err := CheckUpdate(context.Background(), tt.jar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Available != tt.available {
+				t.Errorf("expected available=%v, got %v (latest %s)", tt.available, u.Available, u.Latest.ID)
+			}
+		})
+	}
+}
+
+func TestSelectBuild(t *testing.T) {
+	builds := []Build{
+		{ID: "30", Channel: ChannelAlpha},
+		{ID: "20", Channel: ChannelBeta},
+		{ID: "10", Channel: ChannelStable},
+		{ID: "5", Channel: ChannelRecommended},
+	}
+	experimentalOnly := []Build{{ID: "2", Channel: ChannelBeta}, {ID: "1", Channel: ChannelAlpha}}
+
+	tests := []struct {
+		name       string
+		builds     []Build
+		policy     BuildPolicy
+		want       string
+		wantReason bool
+		wantErr    bool
+	}{
+		{"default skips experimental", builds, BuildPolicy{Channel: BuildChannelDefault}, "10", true, false},
+		{"experimental takes newest", builds, BuildPolicy{Channel: BuildChannelExperimental}, "30", false, false},
+		{"stable-only skips experimental", builds, BuildPolicy{Channel: BuildChannelStableOnly}, "10", true, false},
+		{"default falls back without stable builds", experimentalOnly, BuildPolicy{Channel: BuildChannelDefault}, "2", false, false},
+		{"stable-only refuses without stable builds", experimentalOnly, BuildPolicy{Channel: BuildChannelStableOnly}, "", false, true},
+		{"pinned", builds, BuildPolicy{Channel: BuildChannelDefault, Pinned: "5"}, "5", true, false},
+		{"pinned experimental", builds, BuildPolicy{Channel: BuildChannelStableOnly, Pinned: "30"}, "30", false, false},
+		{"pinned missing", builds, BuildPolicy{Pinned: "99"}, "", false, true},
+		{"no builds", nil, BuildPolicy{}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, reason, err := selectBuild(tt.builds, tt.policy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got build %s", b.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if b.ID != tt.want {
+				t.Errorf("expected build %s, got %s", tt.want, b.ID)
+			}
+			if (reason != "") != tt.wantReason {
+				t.Errorf("unexpected skip reason %q", reason)
+			}
+		})
+	}
+}
+
+func TestDownloadJar(t *testing.T) {
+	jar, sha := testJar(t)
+	withFillServer(t, fillServer(t, jar, sha))
+	chdirTemp(t)
+
+	name, err := DownloadJar(context.Background(), "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "paper-1.21.4-20.jar" {
+		t.Errorf("expected paper-1.21.4-20.jar, got %s", name)
+	}
+	if saved, _ := utils.LoadChecksumFile(name + ".sha256"); saved != sha {
+		t.Errorf("expected saved checksum %s, got %s", sha, saved)
+	}
+}
+
+func TestDownloadJarChecksumMismatch(t *testing.T) {
+	jar, _ := testJar(t)
+	withFillServer(t, fillServer(t, jar, "0000000000000000000000000000000000000000000000000000000000000000"))
+	chdirTemp(t)
+
+	if _, err := DownloadJar(context.Background(), "1.21.4"); !errors.Is(err, utils.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat("paper-1.21.4-20.jar"); !os.IsNotExist(err) {
+		t.Error("JAR with mismatched checksum should not be installed")
+	}
+}
+
+// countingTransport counts the requests for each path.
+type countingTransport struct {
+	base  http.RoundTripper
+	calls map[string]int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls[req.URL.Path]++
+	return c.base.RoundTrip(req)
+}
+
+func TestDownloadJarCache(t *testing.T) {
+	jar, sha := testJar(t)
+	ts := fillServer(t, jar, sha)
+	withFillServer(t, ts)
+	transport := &countingTransport{base: ts.Client().Transport, calls: map[string]int{}}
+	utils.HTTPClient = &http.Client{Transport: transport}
+
+	oldCache := jarCache
+	SetCache(cache.New(t.TempDir()))
+	t.Cleanup(func() { jarCache = oldCache })
+
+	// Two server directories installing the same build download it once.
+	for i := 0; i < 2; i++ {
+		chdirTemp(t)
+		name, err := DownloadJar(context.Background(), "1.21.4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, _ := os.ReadFile(name); !bytes.Equal(data, jar) {
+			t.Fatal("installed JAR does not match")
+		}
+		if saved, _ := utils.LoadChecksumFile(name + ".sha256"); saved != sha {
+			t.Errorf("expected saved checksum %s, got %s", sha, saved)
+		}
+	}
+	if n := transport.calls["/data/paper-1.21.4-20.jar"]; n != 1 {
+		t.Errorf("expected the JAR to be downloaded once, got %d", n)
+	}
+}
+
+func TestInstallBuildCacheByName(t *testing.T) {
+	jar, _ := testJar(t)
+	sum := sha1.Sum(jar)
+	sha1Sum := hex.EncodeToString(sum[:])
+	downloads := 0
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/" {
-			if _, err := fmt.Fprintln(w, `{"versions": ["1.19", "1.20", "1.21"]}`); err != nil {
-				_ = err
+		downloads++
+		w.Write(jar)
+	}))
+	defer ts.Close()
+	withMockClient(ts.Client(), func() {
+		oldCache := jarCache
+		SetCache(cache.New(t.TempDir()))
+		t.Cleanup(func() { jarCache = oldCache })
+
+		tests := []struct {
+			name          string
+			url           string
+			checksum      utils.Checksum
+			wantDownloads int
+			wantErr       bool
+		}{
+			{"first download", "/server.jar", utils.Checksum{Algorithm: "sha1", Value: sha1Sum}, 1, false},
+			{"cached by name and URL", "/server.jar", utils.Checksum{Algorithm: "sha1", Value: sha1Sum}, 1, false},
+			{"no published checksum", "/server.jar", utils.Checksum{}, 1, false},
+			{"same name from another URL", "/installer/2/server.jar", utils.Checksum{}, 2, false},
+			{"cached JAR no longer published", "/server.jar", utils.Checksum{Algorithm: "sha1", Value: strings.Repeat("0", 40)}, 3, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				chdirTemp(t)
+				build := &Build{Download: Download{Name: "vanilla-1.21.5.jar", URL: ts.URL + tt.url, Checksum: tt.checksum}}
+				if _, err := installBuild(context.Background(), build); (err != nil) != tt.wantErr {
+					t.Fatalf("installBuild() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if downloads != tt.wantDownloads {
+					t.Errorf("expected %d downloads, got %d", tt.wantDownloads, downloads)
+				}
+			})
+		}
+	})
+}
+
+func TestInstallFromCacheSkipsStaleEntries(t *testing.T) {
+	oldCache := jarCache
+	SetCache(cache.New(t.TempDir()))
+	t.Cleanup(func() { jarCache = oldCache })
+	chdirTemp(t)
+
+	const name, url = "vanilla-1.21.5.jar", "https://example.com/server.jar"
+	jar, sha := testJar(t)
+	// An older upload of the same JAR, which the source no longer publishes.
+	stale, staleSum := testJarWith(t, "Manifest-Version: 1.0\nBuild: old\n")
+
+	// The stale entry is the most recently used one.
+	for _, e := range []struct {
+		data []byte
+		sum  string
+	}{{jar, sha}, {stale, staleSum}} {
+		if err := os.WriteFile(name, e.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := jarCache.Add(name, e.sum, url); err != nil {
+			t.Fatal(err)
+		}
+		// The cache may hold a hard link to the file.
+		os.Remove(name)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sum := sha1.Sum(jar)
+	ok, err := installFromCache(Download{Name: name, URL: url, Checksum: utils.Checksum{Algorithm: "sha1", Value: hex.EncodeToString(sum[:])}})
+	if !ok || err != nil {
+		t.Fatalf("expected the older matching entry to be used, got %v (%v)", ok, err)
+	}
+	if data, _ := os.ReadFile(name); !bytes.Equal(data, jar) {
+		t.Error("installed JAR does not match")
+	}
+	if entries, _ := jarCache.List(); len(entries) != 1 || entries[0].SHA256 != sha {
+		t.Errorf("expected the stale entry to be dropped, got %+v", entries)
+	}
+}
+
+func TestDownloadJarOffline(t *testing.T) {
+	jar, sha := testJar(t)
+	ts := fillServer(t, jar, sha)
+	withFillServer(t, ts)
+
+	oldCache := jarCache
+	SetCache(cache.New(t.TempDir()))
+	t.Cleanup(func() { jarCache = oldCache })
+
+	chdirTemp(t)
+	if _, err := DownloadJar(context.Background(), "1.21.4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	utils.SetOffline(true)
+	t.Cleanup(func() { utils.SetOffline(false) })
+	ts.Close()
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"latest", "paper-1.21.4-20.jar"},
+		{"1.21.4", "paper-1.21.4-20.jar"},
+		{"1.20.6", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			chdirTemp(t)
+			name, err := DownloadJar(context.Background(), tt.version)
+			if tt.want == "" {
+				if !errors.Is(err, utils.ErrOffline) {
+					t.Fatalf("expected ErrOffline, got %v", err)
+				}
+				return
+			}
+			if err != nil || name != tt.want {
+				t.Fatalf("expected %s, got %s (%v)", tt.want, name, err)
+			}
+			if saved, _ := utils.LoadChecksumFile(name + ".sha256"); saved != sha {
+				t.Errorf("expected saved checksum %s, got %s", sha, saved)
 			}
-		} else {
+		})
+	}
+}
+
+func TestDownloadJarOfflineMetadata(t *testing.T) {
+	jars := make(map[string][]byte)
+	var builds []Build
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jars[r.URL.Path])
+	}))
+	for _, b := range []struct{ id, channel string }{{"30", ChannelBeta}, {"20", ChannelStable}, {"10", ChannelStable}} {
+		name := "paper-1.21.4-" + b.id + ".jar"
+		jar, sha := testJarWith(t, "Manifest-Version: 1.0\nBuild: "+b.id+"\n")
+		jars["/"+name] = jar
+		builds = append(builds, Build{ID: b.id, Channel: b.channel, Download: Download{
+			Name: name, URL: ts.URL + "/" + name, Checksum: utils.Checksum{Algorithm: "sha256", Value: sha},
+		}})
+	}
+
+	oldProvider, oldCache := provider, jarCache
+	provider = buildsProvider{builds: builds}
+	SetCache(cache.New(t.TempDir()))
+	t.Cleanup(func() { provider, jarCache = oldProvider, oldCache })
+	t.Cleanup(func() { SetBuildPolicy(BuildPolicy{}) })
+
+	// Builds 10 and 20 are downloaded while online, 30 never is.
+	withMockClient(ts.Client(), func() {
+		for _, policy := range []BuildPolicy{{Pinned: "10"}, {}} {
+			SetBuildPolicy(policy)
+			chdirTemp(t)
+			if _, err := DownloadJar(context.Background(), "latest"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	ts.Close()
+
+	utils.SetOffline(true)
+	t.Cleanup(func() { utils.SetOffline(false) })
+
+	tests := []struct {
+		name   string
+		policy BuildPolicy
+		want   string
+	}{
+		{"newest cached stable build", BuildPolicy{}, "paper-1.21.4-20.jar"},
+		{"experimental without the newest build cached", BuildPolicy{Channel: BuildChannelExperimental}, "paper-1.21.4-20.jar"},
+		{"pinned", BuildPolicy{Pinned: "10"}, "paper-1.21.4-10.jar"},
+		{"pinned build not cached", BuildPolicy{Pinned: "30"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetBuildPolicy(tt.policy)
+			chdirTemp(t)
+			name, err := DownloadJar(context.Background(), "latest")
+			if tt.want == "" {
+				if !errors.Is(err, utils.ErrOffline) {
+					t.Fatalf("expected ErrOffline, got %v", err)
+				}
+				return
+			}
+			if err != nil || name != tt.want {
+				t.Fatalf("expected %s, got %s (%v)", tt.want, name, err)
+			}
+			if data, _ := os.ReadFile(name); !bytes.Equal(data, jars["/"+name]) {
+				t.Error("installed JAR does not match")
+			}
+		})
+	}
+
+	ver, _, err := CachedVersion("latest")
+	if err != nil || ver.ID != "1.21.4" || ver.MinJava != 21 {
+		t.Errorf("unexpected cached version %+v (%v)", ver, err)
+	}
+}
+
+func TestParseJarName(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		name     string
+		version  string
+		build    string
+		ok       bool
+	}{
+		{NewFillClient("", TypePaper), "paper-1.21.4-120.jar", "1.21.4", "120", true},
+		{NewFillClient("", TypeVelocity), "velocity-3.4.0-SNAPSHOT-500.jar", "3.4.0-SNAPSHOT", "500", true},
+		{NewFillClient("", TypePaper), "purpur-1.21.4-2400.jar", "", "", false},
+		{newPurpurProvider(""), "purpur-1.21.4-2400.jar", "1.21.4", "2400", true},
+		{newFabricProvider(""), "fabric-1.21.5-pre1-0.16.10.jar", "1.21.5-pre1", "0.16.10", true},
+		{newVanillaProvider(""), "vanilla-1.21.4.jar", "1.21.4", "", true},
+		{newVanillaProvider(""), "paper-1.21.4-120.jar", "", "", false},
+	}
+	for _, tt := range tests {
+		version, build, ok := tt.provider.ParseJarName(tt.name)
+		if version != tt.version || build != tt.build || ok != tt.ok {
+			t.Errorf("%s.ParseJarName(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.provider.Name(), tt.name, version, build, ok, tt.version, tt.build, tt.ok)
+		}
+	}
+}
+
+func TestPurpurProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"project": "purpur", "versions": ["1.21.3", "1.21.4"]}`)
+		case "/1.21.4":
+			fmt.Fprint(w, `{"builds": {"latest": "2401", "all": [
+				{"build": "2399", "result": "SUCCESS", "md5": "aaa", "timestamp": 1700000000000, "commits": [{"hash": "c1", "description": "Update upstream"}]},
+				{"build": "2400", "result": "FAILURE", "md5": "bbb"},
+				{"build": "2401", "result": "SUCCESS", "md5": "ccc"}]}}`)
+		default:
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer ts.Close()
+	p := newPurpurProvider(ts.URL)
 
 	withMockClient(ts.Client(), func() {
-		version, err := getLatestVersion(context.Background(), ts.URL)
+		latest, err := p.LatestVersion(context.Background())
+		if err != nil || latest != "1.21.4" {
+			t.Fatalf("expected 1.21.4, got %s (%v)", latest, err)
+		}
+		builds, err := p.Builds(context.Background(), "1.21.4")
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			t.Fatal(err)
 		}
-		if version != "1.21" {
-			t.Errorf("expected 1.21, got %s", version)
+		if len(builds) != 2 || builds[0].ID != "2401" || builds[1].ID != "2399" {
+			t.Fatalf("expected successful builds newest first, got %+v", builds)
+		}
+		dl := builds[0].Download
+		if dl.Name != "purpur-1.21.4-2401.jar" || dl.URL != ts.URL+"/1.21.4/2401/download" || dl.Checksum != (utils.Checksum{Algorithm: "md5", Value: "ccc"}) {
+			t.Errorf("unexpected download: %+v", dl)
+		}
+		if len(builds[1].Commits) != 1 || builds[1].Commits[0].Message != "Update upstream" {
+			t.Errorf("unexpected commits: %+v", builds[1].Commits)
 		}
 	})
 }
 
-func TestGetLatestBuild(t *testing.T) {
+func TestFabricProvider(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains(r.URL.Path, "/builds") {
-			if _, err := fmt.Fprintln(w, `{"builds": [{"build": 10}, {"build": 20}]}`); err != nil {
-				_ = err
-			}
-		} else {
+		switch r.URL.Path {
+		case "/game":
+			fmt.Fprint(w, `[{"version": "25w14a", "stable": false}, {"version": "1.21.5", "stable": true}]`)
+		case "/loader/1.21.5":
+			fmt.Fprint(w, `[{"loader": {"version": "0.17.0-beta.1", "stable": false}}, {"loader": {"version": "0.16.10", "stable": true}}]`)
+		case "/installer":
+			fmt.Fprint(w, `[{"version": "1.1.0", "stable": false}, {"version": "1.0.3", "stable": true}]`)
+		default:
 			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer ts.Close()
+	p := newFabricProvider(ts.URL)
 
 	withMockClient(ts.Client(), func() {
-		build, err := getLatestBuild(context.Background(), ts.URL, "1.21")
+		latest, err := p.LatestVersion(context.Background())
+		if err != nil || latest != "1.21.5" {
+			t.Fatalf("expected latest stable 1.21.5, got %s (%v)", latest, err)
+		}
+		if _, err := p.Version(context.Background(), "1.0"); err == nil {
+			t.Error("expected error for unsupported version")
+		}
+		builds, err := p.Builds(context.Background(), "1.21.5")
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			t.Fatal(err)
+		}
+		if len(builds) != 2 || builds[0].Channel != ChannelBeta || builds[1].Channel != ChannelStable {
+			t.Fatalf("unexpected builds: %+v", builds)
 		}
-		if build != 20 {
-			t.Errorf("expected 20, got %d", build)
+		dl := builds[1].Download
+		if dl.Name != "fabric-1.21.5-0.16.10.jar" || dl.URL != ts.URL+"/loader/1.21.5/0.16.10/1.0.3/server/jar" {
+			t.Errorf("unexpected download: %+v", dl)
 		}
 	})
 }
 
-func TestGetJarName(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if _, err := fmt.Fprintln(w, `{"downloads": {"application": {"name": "paper-1.21-20.jar"}}}`); err != nil {
-			_ = err
+func TestVanillaProvider(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifest.json":
+			fmt.Fprintf(w, `{"latest": {"release": "1.21.5", "snapshot": "25w14a"}, "versions": [
+				{"id": "25w14a", "type": "snapshot", "url": "%[1]s/25w14a.json"},
+				{"id": "1.21.5", "type": "release", "url": "%[1]s/1.21.5.json", "releaseTime": "2025-03-25T12:00:00+00:00"}]}`, ts.URL)
+		case "/1.21.5.json":
+			fmt.Fprintf(w, `{"downloads": {"server": {"sha1": "abc", "size": 100, "url": "%s/server.jar"}}, "javaVersion": {"majorVersion": 21}}`, ts.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
 		}
 	}))
 	defer ts.Close()
+	p := newVanillaProvider(ts.URL + "/manifest.json")
 
 	withMockClient(ts.Client(), func() {
-		name, err := getJarName(context.Background(), ts.URL, "1.21", 20)
+		latest, err := p.LatestVersion(context.Background())
+		if err != nil || latest != "1.21.5" {
+			t.Fatalf("expected 1.21.5, got %s (%v)", latest, err)
+		}
+		ver, err := p.Version(context.Background(), "1.21.5")
+		if err != nil || ver.MinJava != 21 {
+			t.Fatalf("unexpected version %+v (%v)", ver, err)
+		}
+		builds, err := p.Builds(context.Background(), "1.21.5")
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			t.Fatal(err)
+		}
+		if len(builds) != 1 || builds[0].ID != "" || builds[0].Channel != ChannelStable {
+			t.Fatalf("unexpected builds: %+v", builds)
+		}
+		dl := builds[0].Download
+		if dl.Name != "vanilla-1.21.5.jar" || dl.URL != ts.URL+"/server.jar" || dl.Checksum != (utils.Checksum{Algorithm: "sha1", Value: "abc"}) {
+			t.Errorf("unexpected download: %+v", dl)
 		}
-		if name != "paper-1.21-20.jar" {
-			t.Errorf("expected paper-1.21-20.jar, got %s", name)
+		if _, err := p.Version(context.Background(), "0.0.1"); err == nil {
+			t.Error("expected error for unknown version")
 		}
 	})
 }
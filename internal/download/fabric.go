@@ -0,0 +1,119 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+const fabricBase = "https://meta.fabricmc.net/v2/versions"
+
+// fabricProvider downloads the Fabric server launcher, a small JAR that
+// installs the loader and Minecraft server on first start. Its builds are
+// loader versions.
+type fabricProvider struct {
+	baseURL string
+}
+
+func newFabricProvider(baseURL string) *fabricProvider {
+	return &fabricProvider{baseURL: baseURL}
+}
+
+func (p *fabricProvider) Name() string {
+	return TypeFabric
+}
+
+func (p *fabricProvider) ParseJarName(name string) (string, string, bool) {
+	return parseBuildJarName(TypeFabric, name)
+}
+
+type fabricGameVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+func (p *fabricProvider) gameVersions(ctx context.Context) ([]fabricGameVersion, error) {
+	var versions []fabricGameVersion
+	if err := getJSON(ctx, p.baseURL+"/game", &versions); err != nil {
+		return nil, fmt.Errorf("failed to fetch versions: %w", err)
+	}
+	return versions, nil
+}
+
+// LatestVersion returns the newest stable Minecraft release Fabric supports.
+func (p *fabricProvider) LatestVersion(ctx context.Context) (string, error) {
+	versions, err := p.gameVersions(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if v.Stable {
+			return v.Version, nil
+		}
+	}
+	return "", fmt.Errorf("no versions found")
+}
+
+func (p *fabricProvider) Version(ctx context.Context, version string) (*Version, error) {
+	versions, err := p.gameVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return &Version{ID: version}, nil
+		}
+	}
+	return nil, fmt.Errorf("fabric does not support Minecraft %s", version)
+}
+
+func (p *fabricProvider) Builds(ctx context.Context, version string) ([]Build, error) {
+	var loaders []struct {
+		Loader struct {
+			Version string `json:"version"`
+			Stable  bool   `json:"stable"`
+		} `json:"loader"`
+	}
+	if err := getJSON(ctx, p.baseURL+"/loader/"+url.PathEscape(version), &loaders); err != nil {
+		return nil, fmt.Errorf("failed to fetch loader versions: %w", err)
+	}
+
+	installer, err := p.latestInstaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	builds := make([]Build, 0, len(loaders))
+	for _, l := range loaders {
+		channel := ChannelStable
+		if !l.Loader.Stable {
+			channel = ChannelBeta
+		}
+		builds = append(builds, Build{
+			ID:      l.Loader.Version,
+			Channel: channel,
+			Download: Download{
+				Name: fmt.Sprintf("fabric-%s-%s.jar", version, l.Loader.Version),
+				URL: fmt.Sprintf("%s/loader/%s/%s/%s/server/jar", p.baseURL,
+					url.PathEscape(version), url.PathEscape(l.Loader.Version), url.PathEscape(installer)),
+			},
+		})
+	}
+	return builds, nil
+}
+
+func (p *fabricProvider) latestInstaller(ctx context.Context) (string, error) {
+	var installers []struct {
+		Version string `json:"version"`
+		Stable  bool   `json:"stable"`
+	}
+	if err := getJSON(ctx, p.baseURL+"/installer", &installers); err != nil {
+		return "", fmt.Errorf("failed to fetch installer versions: %w", err)
+	}
+	for _, i := range installers {
+		if i.Stable {
+			return i.Version, nil
+		}
+	}
+	return "", fmt.Errorf("no stable Fabric installer found")
+}
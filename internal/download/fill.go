@@ -0,0 +1,173 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	fillBase          = "https://fill.papermc.io/v3"
+	serverDownloadKey = "server:default"
+)
+
+// FillClient talks to the PaperMC Fill (v3) API for a single project. It is
+// the provider for Paper, Folia, Velocity and Waterfall.
+type FillClient struct {
+	baseURL string
+	project string
+}
+
+func NewFillClient(baseURL, project string) *FillClient {
+	return &FillClient{baseURL: baseURL, project: project}
+}
+
+func (c *FillClient) Name() string {
+	return c.project
+}
+
+func (c *FillClient) ParseJarName(name string) (string, string, bool) {
+	return parseBuildJarName(c.project, name)
+}
+
+type fillVersion struct {
+	Version struct {
+		ID      string `json:"id"`
+		Support struct {
+			Status string `json:"status"`
+		} `json:"support"`
+		Java struct {
+			Version struct {
+				Minimum int `json:"minimum"`
+			} `json:"version"`
+		} `json:"java"`
+	} `json:"version"`
+	Builds []int `json:"builds"`
+}
+
+func (v fillVersion) toVersion() Version {
+	builds := make([]string, 0, len(v.Builds))
+	for _, b := range v.Builds {
+		builds = append(builds, strconv.Itoa(b))
+	}
+	return Version{
+		ID:      v.Version.ID,
+		Support: v.Version.Support.Status,
+		MinJava: v.Version.Java.Version.Minimum,
+		Builds:  builds,
+	}
+}
+
+type fillBuild struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Commits []struct {
+		SHA     string    `json:"sha"`
+		Time    time.Time `json:"time"`
+		Message string    `json:"message"`
+	} `json:"commits"`
+	Downloads map[string]struct {
+		Name      string `json:"name"`
+		Checksums struct {
+			SHA256 string `json:"sha256"`
+		} `json:"checksums"`
+		Size int64  `json:"size"`
+		URL  string `json:"url"`
+	} `json:"downloads"`
+}
+
+func (b fillBuild) toBuild() (Build, error) {
+	dl, ok := b.Downloads[serverDownloadKey]
+	if !ok {
+		return Build{}, fmt.Errorf("build %d has no %s download", b.ID, serverDownloadKey)
+	}
+	build := Build{
+		ID:      strconv.Itoa(b.ID),
+		Time:    b.Time,
+		Channel: b.Channel,
+		Download: Download{
+			Name:     dl.Name,
+			Checksum: newChecksum("sha256", dl.Checksums.SHA256),
+			Size:     dl.Size,
+			URL:      dl.URL,
+		},
+	}
+	for _, c := range b.Commits {
+		build.Commits = append(build.Commits, Commit{SHA: c.SHA, Time: c.Time, Message: c.Message})
+	}
+	return build, nil
+}
+
+// LatestVersion returns the newest version that has builds.
+func (c *FillClient) LatestVersion(ctx context.Context) (string, error) {
+	versions, err := c.Versions(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if len(v.Builds) > 0 {
+			return v.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no versions found")
+}
+
+func (c *FillClient) Versions(ctx context.Context) ([]Version, error) {
+	var resp struct {
+		Versions []fillVersion `json:"versions"`
+	}
+	if err := c.get(ctx, "/versions", &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch versions: %w", err)
+	}
+	versions := make([]Version, 0, len(resp.Versions))
+	for _, v := range resp.Versions {
+		versions = append(versions, v.toVersion())
+	}
+	return versions, nil
+}
+
+func (c *FillClient) Version(ctx context.Context, version string) (*Version, error) {
+	var resp fillVersion
+	if err := c.get(ctx, "/versions/"+url.PathEscape(version), &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch version %s: %w", version, err)
+	}
+	v := resp.toVersion()
+	return &v, nil
+}
+
+func (c *FillClient) Builds(ctx context.Context, version string) ([]Build, error) {
+	var resp []fillBuild
+	if err := c.get(ctx, "/versions/"+url.PathEscape(version)+"/builds", &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch builds: %w", err)
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].ID > resp[j].ID })
+	builds := make([]Build, 0, len(resp))
+	for _, b := range resp {
+		build, err := b.toBuild()
+		if err != nil {
+			continue
+		}
+		builds = append(builds, build)
+	}
+	return builds, nil
+}
+
+func (c *FillClient) Build(ctx context.Context, version string, build int) (*Build, error) {
+	var resp fillBuild
+	if err := c.get(ctx, fmt.Sprintf("/versions/%s/builds/%d", url.PathEscape(version), build), &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch build %d: %w", build, err)
+	}
+	b, err := resp.toBuild()
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (c *FillClient) get(ctx context.Context, path string, v interface{}) error {
+	return getJSON(ctx, c.baseURL+"/projects/"+c.project+path, v)
+}
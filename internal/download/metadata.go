@@ -0,0 +1,88 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// metadataDir, inside the download cache, keeps the last version and build
+// lists fetched for each server type, so offline mode can still choose a
+// build and report what the version needs.
+const metadataDir = "metadata"
+
+// latestVersion is the version "latest" last resolved to.
+const latestVersion = "latest"
+
+type metadata struct {
+	Saved   time.Time
+	Version *Version
+	Builds  []Build
+}
+
+func metadataPath(version string) (string, bool) {
+	if jarCache == nil || version == "" || filepath.Base(version) != version || version == ".." {
+		return "", false
+	}
+	return filepath.Join(jarCache.Dir(), metadataDir, provider.Name(), version+".json"), true
+}
+
+// saveMetadata records what was fetched for a version. Failing to save it
+// only affects offline mode, so it is not an error.
+func saveMetadata(name string, m metadata) {
+	path, ok := metadataPath(name)
+	if !ok {
+		return
+	}
+	m.Saved = time.Now()
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		logger.Debug("Failed to save %s %s metadata: %v", provider.Name(), name, err)
+	}
+}
+
+func loadMetadata(name string) (*metadata, error) {
+	path, ok := metadataPath(name)
+	if !ok {
+		return nil, fmt.Errorf("no saved metadata for %s %s", provider.Name(), name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no saved metadata for %s %s", provider.Name(), name)
+		}
+		return nil, fmt.Errorf("failed to read saved metadata: %w", err)
+	}
+	var m metadata
+	if err := json.Unmarshal(data, &m); err != nil || m.Version == nil {
+		return nil, fmt.Errorf("invalid saved metadata for %s %s", provider.Name(), name)
+	}
+	return &m, nil
+}
+
+// CachedVersion returns the version details saved when the version was last
+// fetched, for reporting its support and Java requirement offline.
+func CachedVersion(version string) (*Version, time.Time, error) {
+	if version == latestVersion {
+		latest, err := loadMetadata(latestVersion)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		version = latest.Version.ID
+	}
+	m, err := loadMetadata(version)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return m.Version, m.Saved, nil
+}
@@ -0,0 +1,138 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+// Server types, which are also the prefixes of the JAR file names.
+const (
+	TypePaper     = "paper"
+	TypeFolia     = "folia"
+	TypePurpur    = "purpur"
+	TypeVelocity  = "velocity"
+	TypeWaterfall = "waterfall"
+	TypeFabric    = "fabric"
+	TypeVanilla   = "vanilla"
+)
+
+// Build channels, from least to most stable. Sources without channels
+// report ChannelStable.
+const (
+	ChannelAlpha       = "ALPHA"
+	ChannelBeta        = "BETA"
+	ChannelStable      = "STABLE"
+	ChannelRecommended = "RECOMMENDED"
+)
+
+// Support states of a Minecraft version.
+const (
+	SupportSupported   = "SUPPORTED"
+	SupportDeprecated  = "DEPRECATED"
+	SupportUnsupported = "UNSUPPORTED"
+)
+
+// Provider knows where to find the builds of one kind of server software.
+type Provider interface {
+	// Name is the server type and the prefix of the JAR files it produces.
+	Name() string
+	LatestVersion(ctx context.Context) (string, error)
+	Version(ctx context.Context, version string) (*Version, error)
+	// Builds lists the builds of a version, newest first.
+	Builds(ctx context.Context, version string) ([]Build, error)
+	// ParseJarName extracts the version and build from a downloaded JAR name.
+	ParseJarName(name string) (version, build string, ok bool)
+}
+
+type Version struct {
+	ID      string
+	Support string
+	MinJava int
+	Builds  []string
+}
+
+type Build struct {
+	// ID is the build number, or the loader version for Fabric. Vanilla
+	// versions have a single build with an empty ID.
+	ID       string
+	Time     time.Time
+	Channel  string
+	Commits  []Commit
+	Download Download
+}
+
+type Commit struct {
+	SHA     string
+	Time    time.Time
+	Message string
+}
+
+type Download struct {
+	Name     string
+	Checksum utils.Checksum
+	Size     int64
+	URL      string
+}
+
+func NewProvider(serverType string) (Provider, error) {
+	switch serverType {
+	case TypePaper, TypeFolia, TypeVelocity, TypeWaterfall:
+		return NewFillClient(fillBase, serverType), nil
+	case TypePurpur:
+		return newPurpurProvider(purpurBase), nil
+	case TypeFabric:
+		return newFabricProvider(fabricBase), nil
+	case TypeVanilla:
+		return newVanillaProvider(vanillaManifest), nil
+	default:
+		return nil, fmt.Errorf("unknown server type: %s", serverType)
+	}
+}
+
+// IsProxy reports whether the server type is a proxy rather than a game
+// server, so it has no EULA or worlds.
+func IsProxy(serverType string) bool {
+	return serverType == TypeVelocity || serverType == TypeWaterfall
+}
+
+var buildJarNameRegex = regexp.MustCompile(`^(.+)-([^-]+)\.jar$`)
+
+// parseBuildJarName parses "<prefix>-<version>-<build>.jar".
+func parseBuildJarName(prefix, name string) (string, string, bool) {
+	rest := strings.TrimPrefix(name, prefix+"-")
+	if rest == name {
+		return "", "", false
+	}
+	m := buildJarNameRegex.FindStringSubmatch(rest)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// newChecksum returns the zero Checksum when the source published no value.
+func newChecksum(algorithm, value string) utils.Checksum {
+	if value == "" {
+		return utils.Checksum{}
+	}
+	return utils.Checksum{Algorithm: algorithm, Value: value}
+}
+
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	resp, err := utils.DoRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const purpurBase = "https://api.purpurmc.org/v2/purpur"
+
+type purpurProvider struct {
+	baseURL string
+}
+
+func newPurpurProvider(baseURL string) *purpurProvider {
+	return &purpurProvider{baseURL: baseURL}
+}
+
+func (p *purpurProvider) Name() string {
+	return TypePurpur
+}
+
+func (p *purpurProvider) ParseJarName(name string) (string, string, bool) {
+	return parseBuildJarName(TypePurpur, name)
+}
+
+// LatestVersion returns the last version listed, as Purpur lists them oldest first.
+func (p *purpurProvider) LatestVersion(ctx context.Context) (string, error) {
+	var resp struct {
+		Versions []string `json:"versions"`
+	}
+	if err := getJSON(ctx, p.baseURL, &resp); err != nil {
+		return "", fmt.Errorf("failed to fetch versions: %w", err)
+	}
+	if len(resp.Versions) == 0 {
+		return "", fmt.Errorf("no versions found")
+	}
+	return resp.Versions[len(resp.Versions)-1], nil
+}
+
+type purpurBuild struct {
+	Build   string `json:"build"`
+	Result  string `json:"result"`
+	MD5     string `json:"md5"`
+	Time    int64  `json:"timestamp"`
+	Commits []struct {
+		Hash        string `json:"hash"`
+		Description string `json:"description"`
+		Time        int64  `json:"timestamp"`
+	} `json:"commits"`
+}
+
+func (p *purpurProvider) versionBuilds(ctx context.Context, version string) ([]purpurBuild, error) {
+	var resp struct {
+		Builds struct {
+			All []purpurBuild `json:"all"`
+		} `json:"builds"`
+	}
+	if err := getJSON(ctx, p.baseURL+"/"+url.PathEscape(version)+"?detailed=true", &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch version %s: %w", version, err)
+	}
+	return resp.Builds.All, nil
+}
+
+func (p *purpurProvider) Version(ctx context.Context, version string) (*Version, error) {
+	all, err := p.versionBuilds(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	v := &Version{ID: version}
+	for _, b := range all {
+		v.Builds = append(v.Builds, b.Build)
+	}
+	return v, nil
+}
+
+// Builds returns the successful builds of a version. Purpur has no release
+// channels, so every build is reported as stable.
+func (p *purpurProvider) Builds(ctx context.Context, version string) ([]Build, error) {
+	all, err := p.versionBuilds(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	builds := make([]Build, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		b := all[i]
+		if b.Result != "SUCCESS" {
+			continue
+		}
+		build := Build{
+			ID:      b.Build,
+			Time:    time.UnixMilli(b.Time),
+			Channel: ChannelStable,
+			Download: Download{
+				Name:     fmt.Sprintf("purpur-%s-%s.jar", version, b.Build),
+				Checksum: newChecksum("md5", b.MD5),
+				URL:      fmt.Sprintf("%s/%s/%s/download", p.baseURL, url.PathEscape(version), url.PathEscape(b.Build)),
+			},
+		}
+		for _, c := range b.Commits {
+			build.Commits = append(build.Commits, Commit{SHA: c.Hash, Time: time.UnixMilli(c.Time), Message: c.Description})
+		}
+		builds = append(builds, build)
+	}
+	sortBuildsByNumber(builds)
+	return builds, nil
+}
+
+// sortBuildsByNumber orders builds with numeric IDs newest first.
+func sortBuildsByNumber(builds []Build) {
+	sort.SliceStable(builds, func(i, j int) bool {
+		a, _ := strconv.Atoi(builds[i].ID)
+		b, _ := strconv.Atoi(builds[j].ID)
+		return a > b
+	})
+}
@@ -0,0 +1,131 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const vanillaManifest = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
+
+// vanillaProvider downloads the official server from Mojang's version
+// manifest. Each version has exactly one build.
+type vanillaProvider struct {
+	manifestURL string
+}
+
+func newVanillaProvider(manifestURL string) *vanillaProvider {
+	return &vanillaProvider{manifestURL: manifestURL}
+}
+
+func (p *vanillaProvider) Name() string {
+	return TypeVanilla
+}
+
+func (p *vanillaProvider) ParseJarName(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, TypeVanilla+"-") || !strings.HasSuffix(name, ".jar") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, TypeVanilla+"-"), ".jar"), "", true
+}
+
+type vanillaManifestEntry struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	URL         string    `json:"url"`
+	ReleaseTime time.Time `json:"releaseTime"`
+}
+
+type vanillaManifestResponse struct {
+	Latest struct {
+		Release string `json:"release"`
+	} `json:"latest"`
+	Versions []vanillaManifestEntry `json:"versions"`
+}
+
+func (p *vanillaProvider) manifest(ctx context.Context) (*vanillaManifestResponse, error) {
+	var m vanillaManifestResponse
+	if err := getJSON(ctx, p.manifestURL, &m); err != nil {
+		return nil, fmt.Errorf("failed to fetch version manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func (p *vanillaProvider) LatestVersion(ctx context.Context) (string, error) {
+	m, err := p.manifest(ctx)
+	if err != nil {
+		return "", err
+	}
+	if m.Latest.Release == "" {
+		return "", fmt.Errorf("no versions found")
+	}
+	return m.Latest.Release, nil
+}
+
+type vanillaVersionResponse struct {
+	Downloads struct {
+		Server struct {
+			SHA1 string `json:"sha1"`
+			Size int64  `json:"size"`
+			URL  string `json:"url"`
+		} `json:"server"`
+	} `json:"downloads"`
+	JavaVersion struct {
+		MajorVersion int `json:"majorVersion"`
+	} `json:"javaVersion"`
+}
+
+func (p *vanillaProvider) version(ctx context.Context, version string) (*vanillaManifestEntry, *vanillaVersionResponse, error) {
+	m, err := p.manifest(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range m.Versions {
+		entry := &m.Versions[i]
+		if entry.ID != version {
+			continue
+		}
+		var v vanillaVersionResponse
+		if err := getJSON(ctx, entry.URL, &v); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch version %s: %w", version, err)
+		}
+		if v.Downloads.Server.URL == "" {
+			return nil, nil, fmt.Errorf("minecraft %s has no server download", version)
+		}
+		return entry, &v, nil
+	}
+	return nil, nil, fmt.Errorf("unknown Minecraft version: %s", version)
+}
+
+func (p *vanillaProvider) Version(ctx context.Context, version string) (*Version, error) {
+	_, v, err := p.version(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	return &Version{ID: version, MinJava: v.JavaVersion.MajorVersion}, nil
+}
+
+// Builds returns the single server build of a version. Snapshots are
+// reported on the beta channel.
+func (p *vanillaProvider) Builds(ctx context.Context, version string) ([]Build, error) {
+	entry, v, err := p.version(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	channel := ChannelStable
+	if entry.Type != "release" {
+		channel = ChannelBeta
+	}
+	server := v.Downloads.Server
+	return []Build{{
+		Time:    entry.ReleaseTime,
+		Channel: channel,
+		Download: Download{
+			Name:     fmt.Sprintf("vanilla-%s.jar", version),
+			Checksum: newChecksum("sha1", server.SHA1),
+			Size:     server.Size,
+			URL:      server.URL,
+		},
+	}}, nil
+}
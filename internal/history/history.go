@@ -0,0 +1,175 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	checksumSuffix = ".sha256"
+	holdFile       = ".hold"
+)
+
+var ErrNotFound = errors.New("JAR not found in history")
+
+// Entry is a server JAR that was replaced by an update or a rollback.
+type Entry struct {
+	Name     string
+	Path     string
+	Replaced time.Time
+	Size     int64
+}
+
+// Archive moves jar and its checksum file into dir, recording the current
+// time as the time it was replaced.
+func Archive(dir, jar string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+	dest := filepath.Join(dir, filepath.Base(jar))
+	if err := moveWithChecksum(jar, dest); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", jar, err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(dest, now, now); err != nil {
+		return fmt.Errorf("failed to record archive time: %w", err)
+	}
+	return nil
+}
+
+// Unarchive moves the JAR called name from dir back into destDir.
+func Unarchive(dir, name, destDir string) error {
+	src := filepath.Join(dir, name)
+	if _, err := os.Stat(src); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return err
+	}
+	if err := moveWithChecksum(src, filepath.Join(destDir, name)); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the archived JARs, most recently replaced first.
+func List(dir string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".jar") {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{
+			Name:     f.Name(),
+			Path:     filepath.Join(dir, f.Name()),
+			Replaced: info.ModTime(),
+			Size:     info.Size(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Replaced.After(entries[j].Replaced)
+	})
+	return entries, nil
+}
+
+// Prune removes all but the keep most recently replaced JARs.
+func Prune(dir string, keep int) ([]string, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if keep < 0 || len(entries) <= keep {
+		return nil, nil
+	}
+	var removed []string
+	for _, e := range entries[keep:] {
+		if err := os.Remove(e.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", e.Name, err)
+		}
+		if err := os.Remove(e.Path + checksumSuffix); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove checksum of %s: %w", e.Name, err)
+		}
+		removed = append(removed, e.Name)
+	}
+	return removed, nil
+}
+
+// Switch makes the archived JAR name active in destDir and archives the
+// currently active JAR, if any. Either both moves happen or neither does.
+func Switch(dir, name, active, destDir string) error {
+	if active != "" && filepath.Base(active) == name {
+		return fmt.Errorf("%s is already the active JAR", name)
+	}
+	if err := Unarchive(dir, name, destDir); err != nil {
+		return err
+	}
+	if active == "" {
+		return nil
+	}
+	if err := Archive(dir, active); err != nil {
+		if undoErr := moveWithChecksum(filepath.Join(destDir, name), filepath.Join(dir, name)); undoErr != nil {
+			return fmt.Errorf("%w (and failed to undo: %v)", err, undoErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Hold marks name as deliberately chosen, so automatic updates leave it alone.
+func Hold(dir, name string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, holdFile), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write hold file: %w", err)
+	}
+	return nil
+}
+
+// Held returns the name of the held JAR, or "" if updates are not held.
+func Held(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, holdFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Release lifts a hold set by Hold.
+func Release(dir string) error {
+	if err := os.Remove(filepath.Join(dir, holdFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove hold file: %w", err)
+	}
+	return nil
+}
+
+// moveWithChecksum renames src to dest, taking src's .sha256 file along.
+func moveWithChecksum(src, dest string) error {
+	if err := os.Rename(src, dest); err != nil {
+		return err
+	}
+	if err := os.Rename(src+checksumSuffix, dest+checksumSuffix); err != nil && !os.IsNotExist(err) {
+		if undoErr := os.Rename(dest, src); undoErr != nil {
+			return fmt.Errorf("%w (and failed to undo: %v)", err, undoErr)
+		}
+		return err
+	}
+	return nil
+}
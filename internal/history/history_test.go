@@ -0,0 +1,118 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeJar(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+checksumSuffix, []byte("checksum"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestArchiveListPrune(t *testing.T) {
+	work := t.TempDir()
+	dir := filepath.Join(work, "launcher-versions")
+
+	names := []string{"paper-1.21.4-10.jar", "paper-1.21.4-20.jar", "paper-1.21.4-30.jar"}
+	for i, name := range names {
+		jar := filepath.Join(work, name)
+		writeJar(t, jar)
+		if err := Archive(dir, jar); err != nil {
+			t.Fatal(err)
+		}
+		if exists(jar) || exists(jar+checksumSuffix) {
+			t.Fatalf("%s should have been moved", name)
+		}
+		replaced := time.Now().Add(time.Duration(i) * time.Hour)
+		os.Chtimes(filepath.Join(dir, name), replaced, replaced)
+	}
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Name != names[2] || entries[2].Name != names[0] {
+		t.Fatalf("expected most recently replaced first, got %+v", entries)
+	}
+
+	removed, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != names[0] {
+		t.Errorf("expected %s to be pruned, got %v", names[0], removed)
+	}
+	if exists(filepath.Join(dir, names[0]+checksumSuffix)) {
+		t.Error("checksum of pruned JAR should be removed")
+	}
+
+	if entries, _ := List(filepath.Join(work, "missing")); entries != nil {
+		t.Errorf("expected no entries for missing directory, got %+v", entries)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	work := t.TempDir()
+	dir := filepath.Join(work, "launcher-versions")
+
+	old := filepath.Join(work, "paper-1.21.4-10.jar")
+	writeJar(t, old)
+	if err := Archive(dir, old); err != nil {
+		t.Fatal(err)
+	}
+	active := filepath.Join(work, "paper-1.21.4-20.jar")
+	writeJar(t, active)
+
+	if err := Switch(dir, "paper-1.21.4-10.jar", active, work); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(old) || !exists(old+checksumSuffix) {
+		t.Error("rolled back JAR and checksum should be active")
+	}
+	if exists(active) || !exists(filepath.Join(dir, "paper-1.21.4-20.jar"+checksumSuffix)) {
+		t.Error("previously active JAR should be archived with its checksum")
+	}
+
+	if err := Switch(dir, "paper-1.21.4-99.jar", old, work); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := Switch(dir, "paper-1.21.4-10.jar", old, work); err == nil {
+		t.Error("expected error when switching to the active JAR")
+	}
+}
+
+func TestHold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "launcher-versions")
+	if Held(dir) != "" {
+		t.Fatal("expected no hold")
+	}
+	if err := Hold(dir, "paper-1.21.4-10.jar"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Held(dir); got != "paper-1.21.4-10.jar" {
+		t.Errorf("expected held JAR, got %q", got)
+	}
+	if err := Release(dir); err != nil {
+		t.Fatal(err)
+	}
+	if Held(dir) != "" {
+		t.Error("expected hold to be released")
+	}
+	if _, err := List(dir); err != nil {
+		t.Errorf("hold file should not break listing: %v", err)
+	}
+}
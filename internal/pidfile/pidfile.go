@@ -0,0 +1,63 @@
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var ErrAlreadyRunning = errors.New("launcher is already running")
+
+// Write records the current process in path, refusing to overwrite
+// the PID file of another live launcher.
+func Write(path string) error {
+	if pid, running := Running(path); running && pid != os.Getpid() {
+		return fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, pid)
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes path if it still belongs to the current process.
+func Remove(path string) error {
+	pid, err := Read(path)
+	if err != nil || pid != os.Getpid() {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove PID file: %w", err)
+	}
+	return nil
+}
+
+// Read returns the PID stored in path, or 0 if the file does not exist.
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid PID file: %s", path)
+	}
+	return pid, nil
+}
+
+// Running reports the PID stored in path and whether that process is alive.
+func Running(path string) (int, bool) {
+	pid, err := Read(path)
+	if err != nil || pid == 0 {
+		return 0, false
+	}
+	exists, err := process.PidExists(int32(pid))
+	return pid, err == nil && exists
+}
@@ -0,0 +1,48 @@
+package pidfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWriteAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "launcher.pid")
+
+	if pid, running := Running(path); running || pid != 0 {
+		t.Errorf("expected no process, got pid %d (running: %v)", pid, running)
+	}
+
+	if err := Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if pid, running := Running(path); !running || pid != os.Getpid() {
+		t.Errorf("expected current process, got pid %d (running: %v)", pid, running)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected PID file to be removed")
+	}
+}
+
+func TestWriteRefusesLiveProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "launcher.pid")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(path); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("expected ErrAlreadyRunning, got %v", err)
+	}
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("expected PID file of another process to be kept")
+	}
+}
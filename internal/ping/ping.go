@@ -0,0 +1,272 @@
+package ping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+const (
+	DefaultPort    = 25565
+	DefaultTimeout = 5 * time.Second
+
+	// -1 asks the server to report its own protocol version.
+	handshakeProtocol = -1
+	stateStatus       = 1
+	maxResponseSize   = 2 * 1024 * 1024
+)
+
+type Player struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// Status is the Server List Ping response.
+type Status struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int      `json:"max"`
+		Online int      `json:"online"`
+		Sample []Player `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+
+	Latency time.Duration `json:"-"`
+}
+
+// MOTD returns the description as plain text without formatting codes.
+func (s *Status) MOTD() string {
+	return utils.StripFormatting(flattenChat(s.Description))
+}
+
+type chatComponent struct {
+	Text  string            `json:"text"`
+	Extra []json.RawMessage `json:"extra"`
+}
+
+// flattenChat converts a chat component, which may be a plain string,
+// an object or an array, into its text.
+func flattenChat(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return ""
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	case '[':
+		var parts []json.RawMessage
+		if err := json.Unmarshal(raw, &parts); err == nil {
+			var sb strings.Builder
+			for _, p := range parts {
+				sb.WriteString(flattenChat(p))
+			}
+			return sb.String()
+		}
+	case '{':
+		var c chatComponent
+		if err := json.Unmarshal(raw, &c); err == nil {
+			var sb strings.Builder
+			sb.WriteString(c.Text)
+			for _, e := range c.Extra {
+				sb.WriteString(flattenChat(e))
+			}
+			return sb.String()
+		}
+	}
+	return ""
+}
+
+// Ping performs the Server List Ping handshake against addr and measures
+// the round trip of a ping/pong exchange.
+func Ping(ctx context.Context, addr string) (*Status, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", portStr)
+	}
+
+	dialer := net.Dialer{Timeout: DefaultTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(DefaultTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, handshakeProtocol)
+	writeString(&handshake, host)
+	binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, stateStatus)
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	id, payload, err := readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status response: %w", err)
+	}
+	if id != 0x00 {
+		return nil, fmt.Errorf("unexpected packet id 0x%02x in status response", id)
+	}
+	body, err := readString(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal([]byte(body), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status response: %w", err)
+	}
+
+	token := rand.Int63()
+	var ping bytes.Buffer
+	writeVarInt(&ping, 0x01)
+	binary.Write(&ping, binary.BigEndian, token)
+
+	sent := time.Now()
+	if err := writePacket(conn, ping.Bytes()); err != nil {
+		return &status, nil
+	}
+	id, payload, err = readPacket(r)
+	if err != nil || id != 0x01 || len(payload) != 8 || int64(binary.BigEndian.Uint64(payload)) != token {
+		// Some proxies close the connection instead of answering the ping.
+		return &status, nil
+	}
+	status.Latency = time.Since(sent)
+
+	return &status, nil
+}
+
+// AddressFromProperties returns the address clients use to reach the
+// server configured in server.properties.
+func AddressFromProperties(props map[string]string) (string, error) {
+	port := DefaultPort
+	if v := props["server-port"]; v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid server-port: %s", v)
+		}
+		port = p
+	}
+	host := props["server-ip"]
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+func writePacket(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	writeVarInt(&buf, int32(len(data)))
+	buf.Write(data)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to send packet: %w", err)
+	}
+	return nil
+}
+
+func readPacket(r io.ByteReader) (int32, []byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length <= 0 || length > maxResponseSize {
+		return 0, nil, fmt.Errorf("invalid packet length: %d", length)
+	}
+
+	data := make([]byte, length)
+	for i := range data {
+		if data[i], err = r.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	br := bytes.NewReader(data)
+	id, err := readVarInt(br)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, data[len(data)-br.Len():], nil
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+		buf.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("VarInt is too big")
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeVarInt(buf, int32(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("invalid string length: %d", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
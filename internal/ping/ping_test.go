@@ -0,0 +1,115 @@
+package ping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+)
+
+const statusJSON = `{
+	"version": {"name": "Paper 1.21.4", "protocol": 769},
+	"players": {"max": 20, "online": 2, "sample": [{"name": "Steve", "id": "1"}, {"name": "Alex", "id": "2"}]},
+	"description": {"text": "§aHello ", "extra": [{"text": "World"}, "!"]}
+}`
+
+func serveStatus(t *testing.T, answerPing bool) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		// handshake, status request
+		for i := 0; i < 2; i++ {
+			if _, _, err := readPacket(r); err != nil {
+				return
+			}
+		}
+		var resp bytes.Buffer
+		writeVarInt(&resp, 0x00)
+		writeString(&resp, statusJSON)
+		writePacket(conn, resp.Bytes())
+
+		id, payload, err := readPacket(r)
+		if err != nil || id != 0x01 || !answerPing {
+			return
+		}
+		var pong bytes.Buffer
+		writeVarInt(&pong, 0x01)
+		pong.Write(payload)
+		writePacket(conn, pong.Bytes())
+	}()
+
+	return l.Addr().String()
+}
+
+func TestPing(t *testing.T) {
+	status, err := Ping(context.Background(), serveStatus(t, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version.Name != "Paper 1.21.4" || status.Version.Protocol != 769 {
+		t.Errorf("unexpected version: %+v", status.Version)
+	}
+	if status.Players.Online != 2 || status.Players.Max != 20 || len(status.Players.Sample) != 2 {
+		t.Errorf("unexpected players: %+v", status.Players)
+	}
+	if motd := status.MOTD(); motd != "Hello World!" {
+		t.Errorf("expected 'Hello World!', got %q", motd)
+	}
+	if status.Latency <= 0 {
+		t.Error("expected latency to be measured")
+	}
+}
+
+func TestPingWithoutPong(t *testing.T) {
+	status, err := Ping(context.Background(), serveStatus(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Latency != 0 {
+		t.Errorf("expected no latency, got %s", status.Latency)
+	}
+}
+
+func TestVarInt(t *testing.T) {
+	for _, v := range []int32{0, 1, 127, 128, 25565, 2097151, -1} {
+		var buf bytes.Buffer
+		writeVarInt(&buf, v)
+		got, err := readVarInt(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v {
+			t.Errorf("round trip of %d returned %d", v, got)
+		}
+	}
+}
+
+func TestFlattenChat(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"A Minecraft Server"`, "A Minecraft Server"},
+		{`{"text": "A", "extra": [{"text": "B"}]}`, "AB"},
+		{`[{"text": "A"}, "B"]`, "AB"},
+		{``, ""},
+	}
+	for _, tt := range tests {
+		if got := flattenChat([]byte(tt.raw)); got != tt.want {
+			t.Errorf("flattenChat(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
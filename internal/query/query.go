@@ -0,0 +1,209 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout = 5 * time.Second
+
+	typeHandshake byte = 0x09
+	typeStat      byte = 0x00
+
+	sessionIDMask  = 0x0F0F0F0F
+	maxPacketSize  = 65535
+	statPaddingLen = 11 // "splitnum\x00\x80\x00"
+)
+
+var (
+	magic         = []byte{0xFE, 0xFD}
+	playerSection = []byte("\x01player_\x00\x00")
+)
+
+// FullStat is the response to a GameSpy4 full stat request.
+type FullStat struct {
+	MOTD       string   `json:"motd"`
+	GameType   string   `json:"game_type"`
+	Version    string   `json:"version"`
+	ServerMod  string   `json:"server_mod,omitempty"`
+	Plugins    []string `json:"plugins"`
+	Map        string   `json:"map"`
+	NumPlayers int      `json:"num_players"`
+	MaxPlayers int      `json:"max_players"`
+	HostPort   int      `json:"host_port"`
+	HostIP     string   `json:"host_ip"`
+	Players    []string `json:"players"`
+}
+
+// Query requests the full stat from a server with enable-query=true.
+// Unlike Server List Ping it returns every online player.
+func Query(ctx context.Context, addr string) (*FullStat, error) {
+	dialer := net.Dialer{Timeout: DefaultTimeout}
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(DefaultTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	sessionID := rand.Int31() & sessionIDMask
+
+	resp, err := roundTrip(conn, typeHandshake, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("query handshake failed: %w", err)
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(resp, "\x00")), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge token: %q", resp)
+	}
+
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint32(payload, uint32(int32(token)))
+	resp, err = roundTrip(conn, typeStat, sessionID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("query full stat failed: %w", err)
+	}
+
+	return parseFullStat(resp)
+}
+
+func roundTrip(conn net.Conn, typ byte, sessionID int32, payload []byte) ([]byte, error) {
+	req := make([]byte, 0, 7+len(payload))
+	req = append(req, magic...)
+	req = append(req, typ)
+	req = binary.BigEndian.AppendUint32(req, uint32(sessionID))
+	req = append(req, payload...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxPacketSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n < 5 || buf[0] != typ || int32(binary.BigEndian.Uint32(buf[1:5])) != sessionID {
+		return nil, errors.New("unexpected response")
+	}
+	return buf[5:n], nil
+}
+
+func parseFullStat(data []byte) (*FullStat, error) {
+	if len(data) < statPaddingLen {
+		return nil, errors.New("full stat response is too short")
+	}
+	data = data[statPaddingLen:]
+
+	kv := make(map[string]string)
+	for {
+		key, rest, ok := cutString(data)
+		if !ok {
+			return nil, errors.New("truncated key/value section")
+		}
+		data = rest
+		if key == "" {
+			break
+		}
+		value, rest, ok := cutString(data)
+		if !ok {
+			return nil, errors.New("truncated key/value section")
+		}
+		data = rest
+		kv[key] = value
+	}
+
+	if !bytes.HasPrefix(data, playerSection) {
+		return nil, errors.New("missing player section")
+	}
+	data = data[len(playerSection):]
+
+	players := []string{}
+	for {
+		name, rest, ok := cutString(data)
+		if !ok || name == "" {
+			break
+		}
+		data = rest
+		players = append(players, name)
+	}
+
+	stat := &FullStat{
+		MOTD:     kv["hostname"],
+		GameType: kv["gametype"],
+		Version:  kv["version"],
+		Map:      kv["map"],
+		HostIP:   kv["hostip"],
+		Players:  players,
+	}
+	stat.NumPlayers, _ = strconv.Atoi(kv["numplayers"])
+	stat.MaxPlayers, _ = strconv.Atoi(kv["maxplayers"])
+	stat.HostPort, _ = strconv.Atoi(kv["hostport"])
+	stat.ServerMod, stat.Plugins = parsePlugins(kv["plugins"])
+
+	return stat, nil
+}
+
+// parsePlugins splits "Paper on 1.21.4: LuckPerms 5.4; EssentialsX 2.20" into
+// the server mod and its plugin list.
+func parsePlugins(s string) (string, []string) {
+	plugins := []string{}
+	mod, list, found := strings.Cut(s, ":")
+	if !found {
+		return strings.TrimSpace(s), plugins
+	}
+	for _, p := range strings.Split(list, ";") {
+		if p = strings.TrimSpace(p); p != "" {
+			plugins = append(plugins, p)
+		}
+	}
+	return strings.TrimSpace(mod), plugins
+}
+
+func cutString(data []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(data[:i]), data[i+1:], true
+}
+
+// AddressFromProperties returns the query address configured in
+// server.properties, or an error if the query listener is disabled.
+func AddressFromProperties(props map[string]string) (string, error) {
+	if props["enable-query"] != "true" {
+		return "", errors.New("query is disabled (set enable-query=true in server.properties)")
+	}
+
+	port := props["query.port"]
+	if port == "" {
+		port = props["server-port"]
+	}
+	if port == "" {
+		port = "25565"
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid query.port: %s", port)
+	}
+
+	host := props["server-ip"]
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
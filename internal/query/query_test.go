@@ -0,0 +1,113 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func serveQuery(t *testing.T, players []string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	const token = 9513307
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 7 || !bytes.Equal(buf[:2], magic) {
+				continue
+			}
+			typ, session := buf[2], buf[3:7]
+
+			var resp bytes.Buffer
+			resp.WriteByte(typ)
+			resp.Write(session)
+			switch typ {
+			case typeHandshake:
+				resp.WriteString("9513307\x00")
+			case typeStat:
+				if n < 11 || binary.BigEndian.Uint32(buf[7:11]) != token {
+					continue
+				}
+				resp.WriteString("splitnum\x00\x80\x00")
+				for _, kv := range [][2]string{
+					{"hostname", "A Minecraft Server"},
+					{"gametype", "SMP"},
+					{"game_id", "MINECRAFT"},
+					{"version", "1.21.4"},
+					{"plugins", "Paper on 1.21.4: LuckPerms 5.4.102; EssentialsX 2.20.1"},
+					{"map", "world"},
+					{"numplayers", "3"},
+					{"maxplayers", "20"},
+					{"hostport", "25565"},
+					{"hostip", "127.0.0.1"},
+				} {
+					resp.WriteString(kv[0] + "\x00" + kv[1] + "\x00")
+				}
+				resp.WriteString("\x00\x01player_\x00\x00")
+				for _, p := range players {
+					resp.WriteString(p + "\x00")
+				}
+				resp.WriteString("\x00")
+			}
+			conn.WriteTo(resp.Bytes(), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	players := []string{"Steve", "Alex", "Herobrine"}
+	stat, err := Query(context.Background(), serveQuery(t, players))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.MOTD != "A Minecraft Server" || stat.Map != "world" || stat.Version != "1.21.4" {
+		t.Errorf("unexpected stat: %+v", stat)
+	}
+	if stat.NumPlayers != 3 || stat.MaxPlayers != 20 {
+		t.Errorf("unexpected player counts: %d/%d", stat.NumPlayers, stat.MaxPlayers)
+	}
+	if !reflect.DeepEqual(stat.Players, players) {
+		t.Errorf("expected %v, got %v", players, stat.Players)
+	}
+	if stat.ServerMod != "Paper on 1.21.4" {
+		t.Errorf("unexpected server mod: %q", stat.ServerMod)
+	}
+	if want := []string{"LuckPerms 5.4.102", "EssentialsX 2.20.1"}; !reflect.DeepEqual(stat.Plugins, want) {
+		t.Errorf("expected %v, got %v", want, stat.Plugins)
+	}
+}
+
+func TestParsePlugins(t *testing.T) {
+	mod, plugins := parsePlugins("CraftBukkit on Bukkit 1.21")
+	if mod != "CraftBukkit on Bukkit 1.21" || len(plugins) != 0 {
+		t.Errorf("unexpected result: %q %v", mod, plugins)
+	}
+}
+
+func TestAddressFromProperties(t *testing.T) {
+	addr, err := AddressFromProperties(map[string]string{"enable-query": "true", "server-port": "25570"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "127.0.0.1:25570" {
+		t.Errorf("expected query port to default to server-port, got %s", addr)
+	}
+
+	if _, err := AddressFromProperties(map[string]string{}); err == nil {
+		t.Error("expected error when query is disabled")
+	}
+}
@@ -0,0 +1,324 @@
+package rcon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	packetAuth         int32 = 3
+	packetAuthResponse int32 = 2
+	packetExecCommand  int32 = 2
+	packetResponse     int32 = 0
+
+	// Minecraft rejects requests larger than this and splits responses at 4096 bytes.
+	maxCommandSize = 1446
+	maxPacketSize  = 4096 + 14
+	headerSize     = 10
+
+	DefaultPort = 25575
+)
+
+// DefaultTimeout bounds connecting, and commands whose context has no
+// deadline.
+var DefaultTimeout = 10 * time.Second
+
+var (
+	ErrAuthFailed      = errors.New("RCON authentication failed")
+	ErrCommandTooLarge = fmt.Errorf("RCON command exceeds %d bytes", maxCommandSize)
+)
+
+type packet struct {
+	id   int32
+	typ  int32
+	body string
+}
+
+// Client is a Source RCON client. It reconnects if the connection dropped
+// between commands, but never sends a command twice.
+type Client struct {
+	addr     string
+	password string
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int32
+	// deadline bounds the reads and writes of the current request.
+	deadline time.Time
+}
+
+func Dial(ctx context.Context, addr, password string) (*Client, error) {
+	c := &Client{addr: addr, password: password, timeout: DefaultTimeout}
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeConn()
+}
+
+func (c *Client) closeConn() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+	return err
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RCON at %s: %w", c.addr, err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	stop := c.watch(ctx)
+	defer stop()
+	id := c.newID()
+	if err := c.write(packet{id: id, typ: packetAuth, body: c.password}); err != nil {
+		c.closeConn()
+		return err
+	}
+	for {
+		resp, err := c.read()
+		if err != nil {
+			c.closeConn()
+			return err
+		}
+		// Source servers send an empty response value before the auth result.
+		if resp.typ != packetAuthResponse {
+			continue
+		}
+		if resp.id == -1 || resp.id != id {
+			c.closeConn()
+			return ErrAuthFailed
+		}
+		return nil
+	}
+}
+
+// Execute runs a command and returns its full response, reassembling
+// responses that the server split across multiple packets.
+func (c *Client) Execute(ctx context.Context, command string) (string, error) {
+	if len(command) > maxCommandSize {
+		return "", ErrCommandTooLarge
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil && c.closedByServer() {
+		c.closeConn()
+	}
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	resp, sent, err := c.execute(ctx, command)
+	if err != nil && !sent && dropped(err) {
+		// The command never reached the server, so it is safe to send again.
+		c.closeConn()
+		if err := c.connect(ctx); err != nil {
+			return "", fmt.Errorf("failed to reconnect: %w", err)
+		}
+		resp, _, err = c.execute(ctx, command)
+	}
+	if err != nil {
+		// The command may still run and answer late, so its reply must not
+		// be read as the reply to the next command.
+		c.closeConn()
+		return "", contextErr(ctx, err)
+	}
+	return resp, nil
+}
+
+// dropped reports whether err means the connection was closed.
+func dropped(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// contextErr returns the error of ctx if it cut a request short.
+func contextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if _, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// closedByServer reports whether the server closed the connection since the
+// last command, e.g. because it restarted.
+func (c *Client) closedByServer() bool {
+	// A deadline already in the past would fail before reading at all.
+	if err := c.conn.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+		return true
+	}
+	_, err := c.reader.Peek(1)
+	var netErr net.Error
+	return err != nil && !(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// watch bounds the reads and writes on the connection by the deadline of
+// ctx, or by the client's timeout if ctx has none, and interrupts them when
+// ctx is cancelled.
+func (c *Client) watch(ctx context.Context) (stop func()) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	c.deadline = deadline
+	conn := c.conn
+	cancel := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	return func() { cancel() }
+}
+
+// execute sends command and reads its reply. sent reports whether the
+// command was written to the connection.
+func (c *Client) execute(ctx context.Context, command string) (resp string, sent bool, err error) {
+	stop := c.watch(ctx)
+	defer stop()
+
+	id := c.newID()
+	if err := c.write(packet{id: id, typ: packetExecCommand, body: command}); err != nil {
+		return "", false, err
+	}
+	// The server answers requests in order, so the reply to this sentinel
+	// marks the end of a multi-packet response.
+	sentinel := c.newID()
+	if err := c.write(packet{id: sentinel, typ: packetResponse}); err != nil {
+		return "", true, err
+	}
+
+	var sb strings.Builder
+	for {
+		resp, err := c.read()
+		if err != nil {
+			return "", true, err
+		}
+		switch resp.id {
+		case id:
+			sb.WriteString(resp.body)
+		case sentinel:
+			return sb.String(), true, nil
+		case -1:
+			return "", true, ErrAuthFailed
+		}
+	}
+}
+
+func (c *Client) newID() int32 {
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+	return c.nextID
+}
+
+func (c *Client) write(p packet) error {
+	if err := c.conn.SetWriteDeadline(c.deadline); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(encodePacket(p))
+	if err != nil {
+		return fmt.Errorf("failed to send RCON packet: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) read() (packet, error) {
+	if err := c.conn.SetReadDeadline(c.deadline); err != nil {
+		return packet{}, err
+	}
+	p, err := readPacket(c.reader)
+	if err != nil {
+		return packet{}, fmt.Errorf("failed to read RCON packet: %w", err)
+	}
+	return p, nil
+}
+
+func encodePacket(p packet) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(p.body)+headerSize+4))
+	binary.Write(buf, binary.LittleEndian, int32(len(p.body)+headerSize))
+	binary.Write(buf, binary.LittleEndian, p.id)
+	binary.Write(buf, binary.LittleEndian, p.typ)
+	buf.WriteString(p.body)
+	buf.Write([]byte{0, 0})
+	return buf.Bytes()
+}
+
+func readPacket(r io.Reader) (packet, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return packet{}, err
+	}
+	if size < headerSize || size > maxPacketSize {
+		return packet{}, fmt.Errorf("invalid packet size: %d", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return packet{}, err
+	}
+
+	return packet{
+		id:   int32(binary.LittleEndian.Uint32(data[0:4])),
+		typ:  int32(binary.LittleEndian.Uint32(data[4:8])),
+		body: string(bytes.TrimRight(data[8:], "\x00")),
+	}, nil
+}
+
+// AddressFromProperties returns the RCON address and password configured
+// in server.properties.
+func AddressFromProperties(props map[string]string) (string, string, error) {
+	if props["enable-rcon"] != "true" {
+		return "", "", fmt.Errorf("RCON is disabled (set enable-rcon=true in server.properties)")
+	}
+	password := props["rcon.password"]
+	if password == "" {
+		return "", "", fmt.Errorf("rcon.password is not set in server.properties")
+	}
+
+	port := DefaultPort
+	if v := props["rcon.port"]; v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid rcon.port: %s", v)
+		}
+		port = p
+	}
+
+	host := props["server-ip"]
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), password, nil
+}
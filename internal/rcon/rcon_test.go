@@ -0,0 +1,232 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer behaves like the Minecraft RCON listener: it answers unknown
+// packet types with "Unknown request" and splits responses at 4096 bytes.
+type fakeServer struct {
+	listener net.Listener
+	password string
+	handle   func(command string) string
+	conns    chan net.Conn
+}
+
+func newFakeServer(t *testing.T, password string, handle func(string) string) *fakeServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: l, password: password, handle: handle, conns: make(chan net.Conn, 4)}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.conns <- conn
+		go s.serveConn(conn)
+	}
+}
+
+func (s *fakeServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := false
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case p.typ == packetAuth:
+			if p.body != s.password {
+				conn.Write(encodePacket(packet{id: -1, typ: packetAuthResponse}))
+				continue
+			}
+			authed = true
+			conn.Write(encodePacket(packet{id: p.id, typ: packetAuthResponse}))
+		case p.typ == packetExecCommand && authed:
+			resp := s.handle(p.body)
+			for len(resp) > 4096 {
+				conn.Write(encodePacket(packet{id: p.id, typ: packetResponse, body: resp[:4096]}))
+				resp = resp[4096:]
+			}
+			conn.Write(encodePacket(packet{id: p.id, typ: packetResponse, body: resp}))
+		default:
+			conn.Write(encodePacket(packet{id: p.id, typ: packetResponse, body: "Unknown request 0"}))
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	s := newFakeServer(t, "secret", func(cmd string) string {
+		return "ran " + cmd
+	})
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	resp, err := c.Execute(context.Background(), "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "ran list" {
+		t.Errorf("expected 'ran list', got %q", resp)
+	}
+}
+
+func TestExecuteMultiPacket(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	s := newFakeServer(t, "secret", func(string) string { return long })
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	resp, err := c.Execute(context.Background(), "help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != long {
+		t.Errorf("expected %d bytes, got %d", len(long), len(resp))
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	s := newFakeServer(t, "secret", func(string) string { return "" })
+
+	if _, err := Dial(context.Background(), s.addr(), "wrong"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	s := newFakeServer(t, "secret", func(cmd string) string { return cmd })
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	(<-s.conns).Close()
+
+	resp, err := c.Execute(context.Background(), "seed")
+	if err != nil {
+		t.Fatalf("expected reconnect, got %v", err)
+	}
+	if resp != "seed" {
+		t.Errorf("expected 'seed', got %q", resp)
+	}
+}
+
+func TestExecuteSlowCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		wantErr  error
+	}{
+		// The context, not the client's timeout, bounds a command.
+		{"context deadline after the timeout", 5 * time.Second, nil},
+		{"context deadline before the reply", 100 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := make(chan string, 4)
+			s := newFakeServer(t, "secret", func(cmd string) string {
+				runs <- cmd
+				time.Sleep(200 * time.Millisecond)
+				return "Saved the game"
+			})
+			c, err := Dial(context.Background(), s.addr(), "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.timeout = 50 * time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+			defer cancel()
+			if _, err := c.Execute(ctx, "save-all flush"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			time.Sleep(300 * time.Millisecond)
+			if len(runs) != 1 {
+				t.Errorf("expected the command to run once, ran %d times", len(runs))
+			}
+		})
+	}
+}
+
+func TestExecuteTimeoutNotRetried(t *testing.T) {
+	runs := make(chan string, 4)
+	s := newFakeServer(t, "secret", func(cmd string) string {
+		runs <- cmd
+		time.Sleep(200 * time.Millisecond)
+		return ""
+	})
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.timeout = 50 * time.Millisecond
+
+	var netErr net.Error
+	if _, err := c.Execute(context.Background(), "give Steve diamond"); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if len(runs) != 1 {
+		t.Errorf("expected the command to run once, ran %d times", len(runs))
+	}
+}
+
+func TestCommandTooLarge(t *testing.T) {
+	c := &Client{}
+	if _, err := c.Execute(context.Background(), strings.Repeat("a", maxCommandSize+1)); !errors.Is(err, ErrCommandTooLarge) {
+		t.Errorf("expected ErrCommandTooLarge, got %v", err)
+	}
+}
+
+func TestAddressFromProperties(t *testing.T) {
+	addr, password, err := AddressFromProperties(map[string]string{
+		"enable-rcon":   "true",
+		"rcon.port":     "25580",
+		"rcon.password": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "127.0.0.1:25580" || password != "secret" {
+		t.Errorf("unexpected address %s / password %s", addr, password)
+	}
+
+	if _, _, err := AddressFromProperties(map[string]string{"enable-rcon": "false"}); err == nil {
+		t.Error("expected error when RCON is disabled")
+	}
+}
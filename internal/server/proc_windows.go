@@ -0,0 +1,14 @@
+//go:build windows
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcAttr puts the server in its own process group so that Ctrl+C in the
+// console reaches only the launcher, which then stops the server cleanly.
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrStartupTimeout = errors.New("server did not finish starting")
+
+// Readiness tracks whether the current server process has printed its
+// "Done (x.xxxs)!" line. It is reset every time a new process starts.
+type Readiness struct {
+	mu       sync.Mutex
+	ready    chan struct{}
+	isReady  bool
+	started  time.Time
+	duration time.Duration
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{ready: make(chan struct{})}
+}
+
+// Ready returns a channel that is closed once the current server is ready.
+func (r *Readiness) Ready() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ready
+}
+
+func (r *Readiness) IsReady() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isReady
+}
+
+// StartupDuration is the time from process start to the ready line,
+// or zero if the server is not ready yet.
+func (r *Readiness) StartupDuration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.duration
+}
+
+func (r *Readiness) reset(started time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isReady {
+		r.ready = make(chan struct{})
+	}
+	r.isReady = false
+	r.started = started
+	r.duration = 0
+}
+
+func (r *Readiness) markReady(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isReady {
+		return false
+	}
+	r.isReady = true
+	r.duration = now.Sub(r.started)
+	close(r.ready)
+	return true
+}
+
+// parseReportedStartup converts the seconds in "Done (12.345s)!" to a duration.
+func parseReportedStartup(seconds string) (time.Duration, bool) {
+	secs, err := strconv.ParseFloat(strings.ReplaceAll(seconds, ",", "."), 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -3,18 +3,23 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/console"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
 	"github.com/shirou/gopsutil/v3/mem"
 )
 
 var (
 	javaVersionRegex = regexp.MustCompile(`"([^"]+)"|version\s+"?([0-9.]+)"?|(\d+\.\d+\.\d+)|(\d+)`)
+	worldsSavedRegex = regexp.MustCompile(`All dimensions are saved`)
+	serverDoneRegex  = regexp.MustCompile(`Done \((\d+(?:[.,]\d+)?)s\)!`)
 )
 
 const (
@@ -23,6 +28,8 @@ const (
 	minRAMForZGC            = 4
 	javaCmd                 = "java"
 	gracefulShutdownTimeout = 30 * time.Second
+	defaultStopCommand      = "stop"
+	defaultStopTimeout      = 60 * time.Second
 )
 
 var aikarFlags = []string{
@@ -190,22 +197,46 @@ func CalculateSmartRAM(configMax, percentage, minRAM, available int) int {
 	return calculated
 }
 
-func RunServer(ctx context.Context, jarFile string, minRAM, maxRAM int, useZGC bool, javaPath string, javaVersion int, serverArgs []string) error {
-	if javaPath == "" {
-		javaPath = javaCmd
-	}
+type Options struct {
+	JarFile     string
+	MinRAM      int
+	MaxRAM      int
+	UseZGC      bool
+	JavaPath    string
+	JavaVersion int
+	ServerArgs  []string
+
+	// Console receives the server's output and forwards commands to it.
+	// A console that only mirrors to stdout is used when nil.
+	Console *console.Console
+
+	// StopCommand is written to the server console on shutdown.
+	// StopTimeout bounds how long to wait for it before signalling the process.
+	StopCommand string
+	StopTimeout time.Duration
+
+	// Readiness is updated when the server finishes starting. If it does not
+	// within StartupTimeout the server is stopped and ErrStartupTimeout returned.
+	Readiness      *Readiness
+	StartupTimeout time.Duration
+	// ReadyPattern matches the line that marks the server as started. The
+	// first submatch, if any, is the startup time in seconds the server
+	// reported. Defaults to Minecraft's "Done (x.xxxs)!".
+	ReadyPattern *regexp.Regexp
+}
 
+func buildArgs(opts Options) ([]string, error) {
 	args := []string{
-		fmt.Sprintf("-Xms%dG", minRAM),
-		fmt.Sprintf("-Xmx%dG", maxRAM),
+		fmt.Sprintf("-Xms%dG", opts.MinRAM),
+		fmt.Sprintf("-Xmx%dG", opts.MaxRAM),
 	}
 
-	if useZGC {
-		if javaVersion < minJavaVersionZGC {
-			return fmt.Errorf("ZGC requires Java %d or higher, found Java %d", minJavaVersionZGC, javaVersion)
+	if opts.UseZGC {
+		if opts.JavaVersion < minJavaVersionZGC {
+			return nil, fmt.Errorf("ZGC requires Java %d or higher, found Java %d", minJavaVersionZGC, opts.JavaVersion)
 		}
 
-		if javaVersion < 17 {
+		if opts.JavaVersion < 17 {
 			filteredFlags := make([]string, 0, len(zgcFlags)-1)
 			for _, flag := range zgcFlags {
 				if !strings.Contains(flag, "ZGenerational") {
@@ -219,7 +250,7 @@ func RunServer(ctx context.Context, jarFile string, minRAM, maxRAM int, useZGC b
 			logger.Info("Using Z Garbage Collector (ZGC)")
 		}
 
-		if maxRAM < minRAMForZGC {
+		if opts.MaxRAM < minRAMForZGC {
 			logger.Warn("ZGC enabled but MaxRAM < %dGB, G1GC may perform better", minRAMForZGC)
 		}
 	} else {
@@ -227,45 +258,177 @@ func RunServer(ctx context.Context, jarFile string, minRAM, maxRAM int, useZGC b
 		args = append(args, aikarFlags...)
 	}
 
-	args = append(args, "-jar", jarFile)
-	args = append(args, serverArgs...)
+	args = append(args, "-jar", opts.JarFile)
+	args = append(args, opts.ServerArgs...)
+	return args, nil
+}
+
+func RunServer(ctx context.Context, opts Options) error {
+	javaPath := opts.JavaPath
+	if javaPath == "" {
+		javaPath = javaCmd
+	}
+
+	args, err := buildArgs(opts)
+	if err != nil {
+		return err
+	}
+
+	con := opts.Console
+	if con == nil {
+		con = console.New(os.Stdout)
+	}
 
 	cmd := exec.Command(javaPath, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	setProcAttr(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
 
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
+	unbind := con.Bind(stdin)
+	defer unbind()
+
+	readiness := opts.Readiness
+	if readiness == nil {
+		readiness = NewReadiness()
+	}
+	readiness.reset(time.Now())
+	defer readiness.reset(time.Time{})
+
+	readyPattern := opts.ReadyPattern
+	if readyPattern == nil {
+		readyPattern = serverDoneRegex
+	}
+	unsubscribe := con.Subscribe(readyPattern, func(match []string) {
+		if !readiness.markReady(time.Now()) {
+			return
+		}
+		if len(match) < 2 {
+			logger.Info("Server is ready (started in %s)", readiness.StartupDuration().Round(time.Millisecond))
+		} else if reported, ok := parseReportedStartup(match[1]); ok {
+			logger.Info("Server is ready (started in %s, server reported %s)", readiness.StartupDuration().Round(time.Millisecond), reported)
+		} else {
+			logger.Info("Server is ready (started in %s)", readiness.StartupDuration().Round(time.Millisecond))
+		}
+	})
+	defer unsubscribe()
+
+	var pumps sync.WaitGroup
+	for _, r := range []io.Reader{stdout, stderr} {
+		pumps.Add(1)
+		go func(r io.Reader) {
+			defer pumps.Done()
+			if err := con.Pump(r); err != nil {
+				logger.Warn("Failed to read server output: %v", err)
+			}
+		}(r)
+	}
+
 	done := make(chan error, 1)
 	go func() {
+		pumps.Wait()
 		done <- cmd.Wait()
 	}()
 
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("server stopped with error: %w", err)
-		}
-		return nil
-	case <-ctx.Done():
-		logger.Info("Stopping server...")
+	var startupTimeout <-chan time.Time
+	if opts.StartupTimeout > 0 {
+		timer := time.NewTimer(opts.StartupTimeout)
+		defer timer.Stop()
+		startupTimeout = timer.C
+	}
+	ready := readiness.Ready()
 
-		if err := cmd.Process.Signal(os.Interrupt); err != nil {
-			if !strings.Contains(err.Error(), "not supported by windows") {
-				logger.Warn("Failed to send signal to process: %v", err)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("server stopped with error: %w", err)
+			}
+			return nil
+		case <-ready:
+			ready = nil
+			startupTimeout = nil
+		case <-startupTimeout:
+			logger.Error("Server did not finish starting within %s", opts.StartupTimeout)
+			shutdown(cmd, con, opts, done)
+			return fmt.Errorf("%w within %s", ErrStartupTimeout, opts.StartupTimeout)
+		case <-ctx.Done():
+			if !shutdown(cmd, con, opts, done) {
+				return ctx.Err()
 			}
+			return nil
 		}
+	}
+}
+
+// shutdown asks the server to stop through its console so it can save the
+// worlds, and escalates to a signal and then a kill if it does not exit.
+// It reports whether the server exited on its own.
+func shutdown(cmd *exec.Cmd, con *console.Console, opts Options, done <-chan error) bool {
+	stopCommand := opts.StopCommand
+	if stopCommand == "" {
+		stopCommand = defaultStopCommand
+	}
+	stopTimeout := opts.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = defaultStopTimeout
+	}
+
+	saved := make(chan struct{})
+	var once sync.Once
+	unsubscribe := con.Subscribe(worldsSavedRegex, func([]string) {
+		once.Do(func() { close(saved) })
+	})
+	defer unsubscribe()
 
+	logger.Info("Stopping server (sending %q)...", stopCommand)
+	if err := con.Send(stopCommand); err != nil {
+		logger.Warn("Failed to send stop command: %v", err)
+	}
+
+	timeout := time.After(stopTimeout)
+wait:
+	for {
 		select {
 		case <-done:
-			return nil
-		case <-time.After(gracefulShutdownTimeout):
-			logger.Warn("Server did not stop in time, killing...")
-			cmd.Process.Kill()
-			return ctx.Err()
+			return true
+		case <-saved:
+			logger.Info("Worlds saved, waiting for server to exit...")
+			saved = nil
+		case <-timeout:
+			break wait
+		}
+	}
+
+	logger.Warn("Server did not stop within %s, sending interrupt...", stopTimeout)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		if !strings.Contains(err.Error(), "not supported by windows") {
+			logger.Warn("Failed to send signal to process: %v", err)
+		}
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(gracefulShutdownTimeout):
+		logger.Warn("Server did not stop in time, killing...")
+		if err := cmd.Process.Kill(); err != nil {
+			logger.Warn("Failed to kill server process: %v", err)
 		}
+		return false
 	}
 }
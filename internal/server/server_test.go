@@ -1,7 +1,11 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestExtractJavaVersion(t *testing.T) {
@@ -50,3 +54,162 @@ func TestParseJavaVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestSupervise(t *testing.T) {
+	policy := RestartPolicy{
+		Enabled:     true,
+		Delay:       time.Millisecond,
+		MaxDelay:    4 * time.Millisecond,
+		CrashLimit:  3,
+		CrashWindow: time.Minute,
+	}
+	errCrash := errors.New("exit status 1")
+
+	t.Run("clean exit", func(t *testing.T) {
+		runs := 0
+		err := Supervise(context.Background(), policy, func(context.Context) error {
+			runs++
+			return nil
+		})
+		if err != nil || runs != 1 {
+			t.Errorf("Supervise() = %v after %d runs, want nil after 1 run", err, runs)
+		}
+	})
+
+	t.Run("restart after crash", func(t *testing.T) {
+		runs := 0
+		err := Supervise(context.Background(), policy, func(context.Context) error {
+			runs++
+			if runs == 1 {
+				return errCrash
+			}
+			return nil
+		})
+		if err != nil || runs != 2 {
+			t.Errorf("Supervise() = %v after %d runs, want nil after 2 runs", err, runs)
+		}
+	})
+
+	t.Run("crash loop", func(t *testing.T) {
+		runs := 0
+		err := Supervise(context.Background(), policy, func(context.Context) error {
+			runs++
+			return errCrash
+		})
+		if !errors.Is(err, ErrCrashLoop) {
+			t.Errorf("expected ErrCrashLoop, got %v", err)
+		}
+		if runs != policy.CrashLimit {
+			t.Errorf("expected %d runs, got %d", policy.CrashLimit, runs)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		runs := 0
+		disabled := policy
+		disabled.Enabled = false
+		err := Supervise(context.Background(), disabled, func(context.Context) error {
+			runs++
+			return errCrash
+		})
+		if !errors.Is(err, errCrash) || runs != 1 {
+			t.Errorf("Supervise() = %v after %d runs, want crash error after 1 run", err, runs)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		runs := 0
+		err := Supervise(ctx, policy, func(context.Context) error {
+			runs++
+			cancel()
+			return errCrash
+		})
+		if err == nil || runs != 1 {
+			t.Errorf("Supervise() = %v after %d runs, want error after 1 run", err, runs)
+		}
+	})
+}
+
+func TestNextRestartDelay(t *testing.T) {
+	if got := nextRestartDelay(5*time.Second, time.Minute); got != 10*time.Second {
+		t.Errorf("expected 10s, got %s", got)
+	}
+	if got := nextRestartDelay(40*time.Second, time.Minute); got != time.Minute {
+		t.Errorf("expected delay capped at 1m, got %s", got)
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	args, err := buildArgs(Options{JarFile: "paper.jar", MinRAM: 2, MaxRAM: 4, JavaVersion: 21, ServerArgs: []string{"nogui"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args[0] != "-Xms2G" || args[1] != "-Xmx4G" {
+		t.Errorf("unexpected memory flags: %v", args[:2])
+	}
+	if tail := strings.Join(args[len(args)-3:], " "); tail != "-jar paper.jar nogui" {
+		t.Errorf("unexpected trailing args: %s", tail)
+	}
+
+	if _, err := buildArgs(Options{UseZGC: true, JavaVersion: 8}); err == nil {
+		t.Error("expected error for ZGC on Java 8")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	r := NewReadiness()
+	start := time.Now()
+	r.reset(start)
+
+	if r.IsReady() {
+		t.Fatal("expected not ready after reset")
+	}
+	if !r.markReady(start.Add(3 * time.Second)) {
+		t.Fatal("expected first markReady to succeed")
+	}
+	if r.markReady(start.Add(4 * time.Second)) {
+		t.Error("expected second markReady to be ignored")
+	}
+	select {
+	case <-r.Ready():
+	default:
+		t.Error("expected Ready channel to be closed")
+	}
+	if d := r.StartupDuration(); d != 3*time.Second {
+		t.Errorf("expected 3s, got %s", d)
+	}
+
+	r.reset(time.Now())
+	select {
+	case <-r.Ready():
+		t.Error("expected new Ready channel after reset")
+	default:
+	}
+}
+
+func TestServerDoneRegex(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Duration
+		ok   bool
+	}{
+		{`[12:00:00 INFO]: Done (12.345s)! For help, type "help"`, 12345 * time.Millisecond, true},
+		{`[12:00:00] [Server thread/INFO]: Done (3,5s)! For help, type "help"`, 3500 * time.Millisecond, true},
+		{`[12:00:00 INFO]: Preparing spawn area: 42%`, 0, false},
+	}
+
+	for _, tt := range tests {
+		match := serverDoneRegex.FindStringSubmatch(tt.line)
+		if (match != nil) != tt.ok {
+			t.Errorf("match(%q) = %v, want %v", tt.line, match != nil, tt.ok)
+			continue
+		}
+		if match == nil {
+			continue
+		}
+		if got, _ := parseReportedStartup(match[1]); got != tt.want {
+			t.Errorf("parseReportedStartup(%q) = %s, want %s", match[1], got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+const restartBackoff = 2.0
+
+var ErrCrashLoop = errors.New("server is crash looping")
+
+// RestartPolicy controls how Supervise reacts when the server exits with an error.
+// A run that stays up longer than CrashWindow resets the backoff delay.
+type RestartPolicy struct {
+	Enabled     bool
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	CrashLimit  int
+	CrashWindow time.Duration
+}
+
+// Supervise calls run until it exits cleanly, the context is cancelled,
+// or CrashLimit crashes happen within CrashWindow.
+func Supervise(ctx context.Context, policy RestartPolicy, run func(context.Context) error) error {
+	var crashes []time.Time
+	delay := policy.Delay
+
+	for {
+		started := time.Now()
+		err := run(ctx)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !policy.Enabled {
+			return err
+		}
+
+		now := time.Now()
+		crashes = append(pruneCrashes(crashes, now, policy.CrashWindow), now)
+		if policy.CrashLimit > 0 && len(crashes) >= policy.CrashLimit {
+			return fmt.Errorf("%w: %d crashes within %s (last error: %v)", ErrCrashLoop, len(crashes), policy.CrashWindow, err)
+		}
+
+		if now.Sub(started) > policy.CrashWindow {
+			delay = policy.Delay
+		}
+
+		logger.Error("Server crashed: %v", err)
+		logger.Info("Restarting server in %s (crash %d/%d)...", delay, len(crashes), policy.CrashLimit)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = nextRestartDelay(delay, policy.MaxDelay)
+	}
+}
+
+func pruneCrashes(crashes []time.Time, now time.Time, window time.Duration) []time.Time {
+	kept := crashes[:0]
+	for _, t := range crashes {
+		if now.Sub(t) <= window {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func nextRestartDelay(current, maxDelay time.Duration) time.Duration {
+	next := time.Duration(float64(current) * restartBackoff)
+	if maxDelay > 0 && next > maxDelay {
+		return maxDelay
+	}
+	return next
+}
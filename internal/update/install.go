@@ -0,0 +1,148 @@
+package update
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+const (
+	checksumSuffix = ".sha256"
+	verifyTimeout  = 30 * time.Second
+)
+
+var ErrNoAsset = errors.New("release has no build for this platform")
+
+// Asset is a file attached to a GitHub release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// AssetName returns the name of the release binary for a platform, as
+// produced by the release workflow.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("paper-launcher-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (r *ReleaseResponse) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// verifyBinary checks that a downloaded launcher starts before it replaces
+// the running one.
+var verifyBinary = func(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, "help")
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("new launcher does not run: %w", err)
+	}
+	return nil
+}
+
+// Install downloads the release binary for this platform, verifies it
+// against the published .sha256 file and replaces the executable at exe.
+// The replaced binary is kept as exe+".old".
+func Install(ctx context.Context, release *ReleaseResponse, exe string) error {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	bin := release.asset(name)
+	if bin == nil {
+		return fmt.Errorf("%w: %s", ErrNoAsset, name)
+	}
+	sumAsset := release.asset(name + checksumSuffix)
+	if sumAsset == nil {
+		return fmt.Errorf("release %s publishes no checksum for %s, refusing to install it", release.TagName, name)
+	}
+
+	sum, err := fetchChecksum(ctx, sumAsset.URL)
+	if err != nil {
+		return err
+	}
+
+	newExe := exe + ".new"
+	logger.Info("Downloading launcher %s (%s)...", release.TagName, name)
+	if err := utils.DownloadFile(ctx, bin.URL, newExe, utils.Checksum{Algorithm: "sha256", Value: sum}); err != nil {
+		return fmt.Errorf("failed to download launcher: %w", err)
+	}
+
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(exe); err == nil {
+		mode = info.Mode().Perm() | 0100
+	}
+	if err := os.Chmod(newExe, mode); err != nil {
+		os.Remove(newExe)
+		return fmt.Errorf("failed to make launcher executable: %w", err)
+	}
+	if err := verifyBinary(newExe); err != nil {
+		os.Remove(newExe)
+		return err
+	}
+
+	return replaceExecutable(exe, newExe)
+}
+
+// fetchChecksum reads a sha256sum-style file and returns its hash.
+func fetchChecksum(ctx context.Context, url string) (string, error) {
+	resp, err := utils.DoRequest(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file is empty")
+	}
+	if b, err := hex.DecodeString(fields[0]); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid checksum file: %q", fields[0])
+	}
+	return fields[0], nil
+}
+
+// replaceExecutable moves exe aside to exe+".old" and newExe into its place.
+// Renaming works even while exe is running, on Windows too.
+func replaceExecutable(exe, newExe string) error {
+	backup := exe + ".old"
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove previous launcher backup: %v", err)
+	}
+	if err := os.Rename(exe, backup); err != nil {
+		os.Remove(newExe)
+		return fmt.Errorf("failed to back up current launcher: %w", err)
+	}
+	if err := os.Rename(newExe, exe); err != nil {
+		if undoErr := os.Rename(backup, exe); undoErr != nil {
+			return fmt.Errorf("failed to install launcher: %w (and failed to restore %s: %v)", err, backup, undoErr)
+		}
+		os.Remove(newExe)
+		return fmt.Errorf("failed to install launcher: %w", err)
+	}
+	return nil
+}
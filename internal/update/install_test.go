@@ -0,0 +1,92 @@
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
+)
+
+func TestInstall(t *testing.T) {
+	newBinary := []byte("new launcher")
+	sum := sha256.Sum256(newBinary)
+	good := hex.EncodeToString(sum[:])
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+
+	tests := []struct {
+		name     string
+		assets   []string
+		checksum string
+		verify   error
+		wantErr  bool
+	}{
+		{"installs verified binary", []string{name, name + ".sha256"}, good, nil, false},
+		{"checksum mismatch", []string{name, name + ".sha256"}, "00" + good[2:], nil, true},
+		{"no checksum published", []string{name}, good, nil, true},
+		{"no asset for platform", []string{"paper-launcher-plan9-mips"}, good, nil, true},
+		{"binary does not run", []string{name, name + ".sha256"}, good, errors.New("exit status 2"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/" + name:
+					w.Write(newBinary)
+				case "/" + name + ".sha256":
+					fmt.Fprintf(w, "%s  %s\n", tt.checksum, name)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer ts.Close()
+			oldClient, oldVerify := utils.HTTPClient, verifyBinary
+			utils.HTTPClient = ts.Client()
+			verifyBinary = func(string) error { return tt.verify }
+			defer func() { utils.HTTPClient, verifyBinary = oldClient, oldVerify }()
+
+			release := &ReleaseResponse{TagName: "v2.0.0"}
+			for _, a := range tt.assets {
+				release.Assets = append(release.Assets, Asset{Name: a, URL: ts.URL + "/" + a})
+			}
+
+			exe := filepath.Join(t.TempDir(), "paper-launcher")
+			if err := os.WriteFile(exe, []byte("old launcher"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Install(context.Background(), release, exe)
+			got, _ := os.ReadFile(exe)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if string(got) != "old launcher" {
+					t.Error("current launcher should be left in place")
+				}
+				if _, err := os.Stat(exe + ".new"); !os.IsNotExist(err) {
+					t.Error("downloaded binary should be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != "new launcher" {
+				t.Errorf("expected new launcher, got %q", got)
+			}
+			if old, _ := os.ReadFile(exe + ".old"); string(old) != "old launcher" {
+				t.Errorf("expected previous launcher kept, got %q", old)
+			}
+		})
+	}
+}
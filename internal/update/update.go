@@ -22,17 +22,38 @@ var (
 	gitVersionOnce   sync.Once
 )
 
-var githubAPIBase = "https://api.github.com/repos/nevcea/minecraft-server-launcher/releases/latest"
+var githubAPIBase = "https://api.github.com/repos/nevcea/minecraft-server-launcher"
+
+// Release channels for launcher updates.
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+var channel = ChannelStable
 
 type ReleaseResponse struct {
-	TagName string `json:"tag_name"`
-	Body    string `json:"body"`
+	TagName    string  `json:"tag_name"`
+	Body       string  `json:"body"`
+	HTMLURL    string  `json:"html_url"`
+	Prerelease bool    `json:"prerelease"`
+	Draft      bool    `json:"draft"`
+	Assets     []Asset `json:"assets"`
 }
 
 func SetGitHubToken(token string) {
 	githubToken = strings.TrimSpace(token)
 }
 
+// SetChannel selects the releases offered as updates. The beta channel
+// includes pre-releases.
+func SetChannel(c string) {
+	if c == "" {
+		c = ChannelStable
+	}
+	channel = c
+}
+
 func getGitHubToken() string {
 	if githubToken != "" {
 		return githubToken
@@ -68,10 +89,64 @@ func getVersionFromGit() string {
 	return cachedGitVersion
 }
 
+// CheckForUpdate looks for a release newer than the running launcher on the
+// configured channel.
 func CheckForUpdate(ctx context.Context) (bool, *ReleaseResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", githubAPIBase, nil)
+	if utils.Offline() {
+		return false, nil, utils.ErrOffline
+	}
+
+	var release *ReleaseResponse
+	if channel == ChannelBeta {
+		// /releases/latest never returns a pre-release.
+		var releases []ReleaseResponse
+		if err := getGitHub(ctx, "/releases", &releases); err != nil {
+			return false, nil, err
+		}
+		release = newestRelease(releases)
+		if release == nil {
+			return false, nil, nil
+		}
+	} else {
+		release = &ReleaseResponse{}
+		if err := getGitHub(ctx, "/releases/latest", release); err != nil {
+			return false, nil, err
+		}
+	}
+
+	current := normalizeVersion(GetCurrentVersion())
+	latest := normalizeVersion(release.TagName)
+
+	if current == "" || current == "dev" || latest == "" {
+		return false, nil, nil
+	}
+
+	if compareVersions(latest, current) <= 0 {
+		return false, nil, nil
+	}
+
+	return true, release, nil
+}
+
+// newestRelease returns the highest published version in releases.
+func newestRelease(releases []ReleaseResponse) *ReleaseResponse {
+	var newest *ReleaseResponse
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || normalizeVersion(r.TagName) == "" {
+			continue
+		}
+		if newest == nil || compareVersions(normalizeVersion(r.TagName), normalizeVersion(newest.TagName)) > 0 {
+			newest = r
+		}
+	}
+	return newest
+}
+
+func getGitHub(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", githubAPIBase+path, nil)
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	token := getGitHubToken()
@@ -84,7 +159,7 @@ func CheckForUpdate(ctx context.Context) (bool, *ReleaseResponse, error) {
 
 	resp, err := utils.HTTPClient.Do(req)
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to check for updates: %w", err)
+		return fmt.Errorf("failed to check for updates: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -95,81 +170,118 @@ func CheckForUpdate(ctx context.Context) (bool, *ReleaseResponse, error) {
 			msg = ": " + msg
 		}
 		if resp.StatusCode == http.StatusNotFound && token == "" {
-			return false, nil, fmt.Errorf("GitHub API returned 404 (private repo? set LAUNCHER_GITHUB_TOKEN env var)%s", msg)
+			return fmt.Errorf("GitHub API returned 404 (private repo? set LAUNCHER_GITHUB_TOKEN env var)%s", msg)
 		}
-		return false, nil, fmt.Errorf("GitHub API returned status %d%s", resp.StatusCode, msg)
-	}
-
-	var release ReleaseResponse
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return false, nil, fmt.Errorf("failed to parse release info: %w", err)
-	}
-
-	current := normalizeVersion(GetCurrentVersion())
-	latest := normalizeVersion(release.TagName)
-
-	if current == "" || current == "dev" || latest == "" {
-		return false, nil, nil
+		return fmt.Errorf("GitHub API returned status %d%s", resp.StatusCode, msg)
 	}
 
-	if compareVersions(latest, current) <= 0 {
-		return false, nil, nil
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse release info: %w", err)
 	}
-
-	return true, &release, nil
+	return nil
 }
 
 func normalizeVersion(version string) string {
 	return strings.TrimSpace(strings.TrimPrefix(version, "v"))
 }
 
+// compareVersions compares two SemVer versions, returning -1, 0 or 1.
+// Missing core segments count as 0, a pre-release sorts before its release
+// and build metadata is ignored.
 func compareVersions(v1, v2 string) int {
-	var i1, i2 int
-	l1, l2 := len(v1), len(v2)
+	core1, pre1 := splitVersion(v1)
+	core2, pre2 := splitVersion(v2)
 
-	for i1 < l1 || i2 < l2 {
+	for i := 0; i < len(core1) || i < len(core2); i++ {
 		var n1, n2 int
-		valid1, valid2 := true, true
-
-		for i1 < l1 {
-			c := v1[i1]
-			i1++
-			if c == '.' {
-				break
-			}
-			if c >= '0' && c <= '9' {
-				n1 = n1*10 + int(c-'0')
-			} else {
-				valid1 = false
-			}
+		if i < len(core1) {
+			n1 = core1[i]
 		}
-		for i2 < l2 {
-			c := v2[i2]
-			i2++
-			if c == '.' {
-				break
-			}
-			if c >= '0' && c <= '9' {
-				n2 = n2*10 + int(c-'0')
-			} else {
-				valid2 = false
-			}
+		if i < len(core2) {
+			n2 = core2[i]
 		}
-
-		if !valid1 {
-			n1 = 0
+		if n1 != n2 {
+			return cmpInt(n1, n2)
 		}
-		if !valid2 {
-			n2 = 0
+	}
+
+	switch {
+	case pre1 == "" && pre2 == "":
+		return 0
+	case pre1 == "":
+		return 1
+	case pre2 == "":
+		return -1
+	}
+	return comparePrerelease(strings.Split(pre1, "."), strings.Split(pre2, "."))
+}
+
+// splitVersion parses "1.2.3-beta.1+build" into its numeric core and
+// pre-release. Non-numeric core segments count as 0.
+func splitVersion(v string) (core []int, pre string) {
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+	for _, seg := range strings.Split(v, ".") {
+		n, ok := parseNumeric(seg)
+		if !ok {
+			n = 0
 		}
+		core = append(core, n)
+	}
+	return core, pre
+}
 
-		if n1 != n2 {
-			if n1 > n2 {
-				return 1
+// comparePrerelease applies the SemVer precedence rules: numeric identifiers
+// compare numerically and sort before alphanumeric ones, which compare in
+// ASCII order, and a shorter list of otherwise equal identifiers sorts first.
+func comparePrerelease(ids1, ids2 []string) int {
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		a, b := ids1[i], ids2[i]
+		na, aNum := parseNumeric(a)
+		nb, bNum := parseNumeric(b)
+		switch {
+		case aNum && bNum:
+			if na != nb {
+				return cmpInt(na, nb)
 			}
+		case aNum:
 			return -1
+		case bNum:
+			return 1
+		default:
+			if c := strings.Compare(a, b); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpInt(len(ids1), len(ids2))
+}
+
+func parseNumeric(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
 		}
+		n = n*10 + int(c-'0')
 	}
+	return n, true
+}
 
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
 	return 0
 }
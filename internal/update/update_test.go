@@ -48,6 +48,18 @@ func TestCompareVersions(t *testing.T) {
 		{"2.0.0", "1.9.9", 1, "major beats minor"},
 		{"1.0", "1.0.0", 0, "different length same"},
 		{"1.1", "1.0.0", 1, "different length newer"},
+		{"1.10.0", "1.9.0", 1, "numeric not lexical"},
+		{"1.2.0-beta.1", "1.2.0", -1, "pre-release before release"},
+		{"1.2.0", "1.2.0-rc.1", 1, "release after pre-release"},
+		{"1.2.0-beta.1", "1.1.9", 1, "pre-release after older release"},
+		{"1.2.0-alpha", "1.2.0-alpha.1", -1, "shorter pre-release first"},
+		{"1.2.0-alpha.1", "1.2.0-alpha.beta", -1, "numeric identifier before alphanumeric"},
+		{"1.2.0-alpha.beta", "1.2.0-beta", -1, "alphanumeric identifiers in ASCII order"},
+		{"1.2.0-beta.2", "1.2.0-beta.11", -1, "numeric identifiers compared numerically"},
+		{"1.2.0-rc.1", "1.2.0-beta.11", 1, "rc after beta"},
+		{"1.2.0-beta.1", "1.2.0-beta.1", 0, "equal pre-release"},
+		{"1.2.0+build.5", "1.2.0+build.7", 0, "build metadata ignored"},
+		{"1.2.0-beta.1+exp", "1.2.0-beta.1", 0, "build metadata ignored on pre-release"},
 	}
 
 	for _, tt := range tests {
@@ -93,3 +105,58 @@ func TestCheckForUpdate_OlderVersion(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckForUpdate_Channels(t *testing.T) {
+	releases := []ReleaseResponse{
+		{TagName: "v1.3.0-beta.1", Prerelease: true},
+		{TagName: "v1.4.0", Draft: true},
+		{TagName: "v1.2.0"},
+		{TagName: "v1.3.0-alpha.2", Prerelease: true},
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/releases/latest":
+			json.NewEncoder(w).Encode(releases[2])
+		case "/releases":
+			json.NewEncoder(w).Encode(releases)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+
+	tests := []struct {
+		channel string
+		current string
+		want    string
+	}{
+		{ChannelStable, "1.1.0", "v1.2.0"},
+		{ChannelStable, "1.3.0-beta.1", ""},
+		{ChannelBeta, "1.1.0", "v1.3.0-beta.1"},
+		{ChannelBeta, "1.3.0-alpha.2", "v1.3.0-beta.1"},
+		{ChannelBeta, "1.3.0-beta.1", ""},
+		{ChannelBeta, "1.3.0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel+" "+tt.current, func(t *testing.T) {
+			SetChannel(tt.channel)
+			defer SetChannel(ChannelStable)
+			withMockAPI(handler, tt.current, func() {
+				hasUpdate, release, err := CheckForUpdate(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.want == "" {
+					if hasUpdate {
+						t.Errorf("expected no update, got %s", release.TagName)
+					}
+					return
+				}
+				if !hasUpdate || release.TagName != tt.want {
+					t.Errorf("expected %s, got %v", tt.want, release)
+				}
+			})
+		})
+	}
+}
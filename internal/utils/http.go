@@ -2,10 +2,18 @@ package utils
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
@@ -30,7 +38,33 @@ var HTTPClient = &http.Client{
 	},
 }
 
+// ErrOffline is returned instead of making a request in offline mode.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+var offline bool
+
+// SetOffline makes every request fail immediately with ErrOffline, so hosts
+// without internet access never wait on retries.
+func SetOffline(v bool) {
+	offline = v
+}
+
+func Offline() bool {
+	return offline
+}
+
 func DoRequest(ctx context.Context, url string) (*http.Response, error) {
+	return doRequest(ctx, url, nil)
+}
+
+var errRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+// doRequest is DoRequest with extra request headers. A partial response is
+// accepted when a Range header is sent.
+func doRequest(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	if offline {
+		return nil, ErrOffline
+	}
 	var lastErr error
 	delay := RetryDelay
 
@@ -48,6 +82,9 @@ func DoRequest(ctx context.Context, url string) (*http.Response, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
 		req.Header.Set("User-Agent", UserAgent)
 
 		resp, err := HTTPClient.Do(req)
@@ -63,9 +100,18 @@ func DoRequest(ctx context.Context, url string) (*http.Response, error) {
 			continue
 		}
 
-		if resp.StatusCode == 200 {
+		if resp.StatusCode == http.StatusOK {
 			return resp, nil
 		}
+		if header.Get("Range") != "" {
+			switch resp.StatusCode {
+			case http.StatusPartialContent:
+				return resp, nil
+			case http.StatusRequestedRangeNotSatisfiable:
+				resp.Body.Close()
+				return nil, errRangeNotSatisfiable
+			}
+		}
 
 		lastErr = fmt.Errorf("API returned status %d", resp.StatusCode)
 		resp.Body.Close()
@@ -78,42 +124,253 @@ func DoRequest(ctx context.Context, url string) (*http.Response, error) {
 	return nil, fmt.Errorf("request failed after %d attempts: %w", MaxRetries, lastErr)
 }
 
-func DownloadFile(ctx context.Context, url, filename string) error {
-	resp, err := DoRequest(ctx, url)
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksum is a hash published by a download source. The zero value means
+// the source did not publish one.
+type Checksum struct {
+	Algorithm string // sha256, sha1 or md5
+	Value     string
+}
+
+func (c Checksum) newHash() (hash.Hash, error) {
+	switch strings.ToLower(c.Algorithm) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", c.Algorithm)
+	}
+}
+
+// Verify checks the file at path against the checksum. The zero Checksum
+// accepts any file.
+func (c Checksum) Verify(path string) error {
+	if c.Value == "" {
+		return nil
+	}
+	h, err := c.newHash()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.CopyBuffer(h, file, make([]byte, DownloadBufSize)); err != nil {
+		return fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, strings.TrimSpace(c.Value)) {
+		return fmt.Errorf("%w for %s (%s):\nExpected: %s\nActual: %s", ErrChecksumMismatch, path, c.Algorithm, c.Value, actual)
+	}
+	return nil
+}
+
+// partialDownload records where a .part file came from, so an interrupted
+// download is only resumed against the same resource.
+type partialDownload struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func newPartialDownload(url string, resp *http.Response) partialDownload {
+	return partialDownload{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// validator returns the value for If-Range. Weak ETags cannot be used for
+// range requests, so Last-Modified is used instead.
+func (p partialDownload) validator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+// matches reports whether resp serves the same resource as the partial file.
+func (p partialDownload) matches(resp *http.Response) bool {
+	current := newPartialDownload(p.URL, resp)
+	if p.ETag != "" && current.ETag != "" {
+		return p.ETag == current.ETag
+	}
+	return p.LastModified != "" && p.LastModified == current.LastModified
+}
+
+func loadPartialDownload(path string) (partialDownload, bool) {
+	var p partialDownload
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, false
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, false
+	}
+	return p, true
+}
+
+func savePartialDownload(path string, p partialDownload) error {
+	data, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	return os.WriteFile(path, data, 0644)
+}
 
-	tempFile := filename + ".part"
-	if _, err := os.Stat(tempFile); err == nil {
-		logger.Info("Found incomplete download, removing: %s", tempFile)
-		if err := os.Remove(tempFile); err != nil {
+func removePartialDownload(tempFile string) {
+	for _, path := range []string{tempFile, tempFile + ".meta"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			logger.Warn("Failed to remove incomplete download: %v", err)
 		}
 	}
+}
+
+// DownloadFile downloads url to filename through a temporary file. When
+// expected is set, the file is only installed if its hash matches.
+//
+// If the server supports range requests, an interrupted download leaves its
+// .part file behind and the next call resumes it, provided the ETag or
+// Last-Modified date shows the resource has not changed.
+func DownloadFile(ctx context.Context, url, filename string, expected Checksum) error {
+	resumed, err := downloadFile(ctx, url, filename, expected, true)
+	if resumed && errors.Is(err, ErrChecksumMismatch) {
+		logger.Warn("Resumed download failed checksum validation, downloading again from the start")
+		_, err = downloadFile(ctx, url, filename, expected, false)
+	}
+	return err
+}
+
+func downloadFile(ctx context.Context, url, filename string, expected Checksum, allowResume bool) (resumed bool, err error) {
+	var hasher hash.Hash
+	if expected.Value != "" {
+		h, err := expected.newHash()
+		if err != nil {
+			return false, err
+		}
+		hasher = h
+	}
+
+	tempFile := filename + ".part"
+	metaFile := tempFile + ".meta"
+
+	var offset int64
+	partial, hasPartial := loadPartialDownload(metaFile)
+	if info, err := os.Stat(tempFile); err == nil {
+		if allowResume && hasPartial && partial.URL == url && partial.validator() != "" && info.Size() > 0 {
+			offset = info.Size()
+		} else {
+			logger.Info("Found incomplete download, removing: %s", tempFile)
+			removePartialDownload(tempFile)
+		}
+	}
 
-	out, err := os.Create(tempFile)
+	var resp *http.Response
+	if offset > 0 {
+		header := http.Header{}
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		header.Set("If-Range", partial.validator())
+		resp, err = doRequest(ctx, url, header)
+		if errors.Is(err, errRangeNotSatisfiable) {
+			logger.Info("Cannot resume incomplete download, starting over")
+			removePartialDownload(tempFile)
+			offset = 0
+			resp, err = DoRequest(ctx, url)
+		}
+	} else {
+		resp, err = DoRequest(ctx, url)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return false, err
 	}
+	defer func() { resp.Body.Close() }()
 
-	closed := false
-	defer func() {
-		if !closed {
-			if err := out.Close(); err != nil {
-				logger.Warn("Failed to close temp file: %v", err)
+	if offset > 0 {
+		var start int64 = -1
+		fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start)
+		switch {
+		case resp.StatusCode == http.StatusPartialContent && start == offset && partial.matches(resp):
+			logger.Info("Resuming incomplete download at %.1f MB", float64(offset)/1024/1024)
+			resumed = true
+		case resp.StatusCode == http.StatusOK:
+			// The resource changed (If-Range failed) or the server ignored
+			// the range; either way the body is the whole file.
+			logger.Info("File changed on the server since the incomplete download, starting over")
+			removePartialDownload(tempFile)
+			offset = 0
+		default:
+			logger.Info("Server did not resume the download as requested, starting over")
+			resp.Body.Close()
+			removePartialDownload(tempFile)
+			offset = 0
+			if resp, err = DoRequest(ctx, url); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	var out *os.File
+	if resumed {
+		out, err = os.OpenFile(tempFile, os.O_RDWR, 0644)
+		if err != nil {
+			return false, fmt.Errorf("failed to open temp file: %w", err)
+		}
+		if hasher != nil {
+			if _, err := io.CopyN(hasher, out, offset); err != nil {
+				out.Close()
+				return false, fmt.Errorf("failed to read temp file: %w", err)
 			}
-			if err := os.Remove(tempFile); err != nil && !os.IsNotExist(err) {
-				logger.Warn("Failed to remove temp file: %v", err)
+		}
+		if _, err := out.Seek(offset, io.SeekStart); err != nil {
+			out.Close()
+			return false, fmt.Errorf("failed to seek temp file: %w", err)
+		}
+	} else {
+		out, err = os.Create(tempFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to create temp file: %w", err)
+		}
+	}
+
+	// Keep the partial file for the next attempt only if it can be resumed.
+	resumable := strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") || resumed
+	meta := newPartialDownload(url, resp)
+	if resumable && meta.validator() != "" {
+		if !resumed {
+			if err := savePartialDownload(metaFile, meta); err != nil {
+				logger.Warn("Failed to save download state: %v", err)
+				resumable = false
 			}
 		}
+	} else {
+		resumable = false
+	}
+
+	closed := false
+	defer func() {
+		if closed {
+			return
+		}
+		if err := out.Close(); err != nil {
+			logger.Warn("Failed to close temp file: %v", err)
+		}
+		if resumable {
+			logger.Info("Incomplete download kept, it will be resumed next time: %s", tempFile)
+			return
+		}
+		removePartialDownload(tempFile)
 	}()
 
 	var bar *progressbar.ProgressBar
 	if resp.ContentLength > 0 {
 		bar = progressbar.NewOptions64(
-			resp.ContentLength,
+			offset+resp.ContentLength,
 			progressbar.OptionSetWriter(os.Stdout),
 			progressbar.OptionEnableColorCodes(true),
 			progressbar.OptionShowBytes(true),
@@ -123,6 +380,9 @@ func DownloadFile(ctx context.Context, url, filename string) error {
 				fmt.Fprint(os.Stdout, "\n")
 			}),
 		)
+		if offset > 0 {
+			bar.Set64(offset)
+		}
 	} else {
 		bar = progressbar.NewOptions64(
 			-1,
@@ -135,10 +395,14 @@ func DownloadFile(ctx context.Context, url, filename string) error {
 	}
 
 	buf := make([]byte, DownloadBufSize)
-	var writer io.Writer = out
+	writers := []io.Writer{out}
+	if hasher != nil {
+		writers = append(writers, hasher)
+	}
 	if bar != nil {
-		writer = io.MultiWriter(out, bar)
+		writers = append(writers, bar)
 	}
+	writer := io.MultiWriter(writers...)
 
 	done := make(chan error, 1)
 	go func() {
@@ -148,10 +412,10 @@ func DownloadFile(ctx context.Context, url, filename string) error {
 
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return resumed, ctx.Err()
 	case err := <-done:
 		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
+			return resumed, fmt.Errorf("failed to write file: %w", err)
 		}
 	}
 
@@ -159,22 +423,34 @@ func DownloadFile(ctx context.Context, url, filename string) error {
 		bar.Close()
 	}
 
+	closed = true
 	if err := out.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %w", err)
+		removePartialDownload(tempFile)
+		return resumed, fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if hasher != nil {
+		actual := hex.EncodeToString(hasher.Sum(nil))
+		if !strings.EqualFold(actual, strings.TrimSpace(expected.Value)) {
+			removePartialDownload(tempFile)
+			return resumed, fmt.Errorf("%w for %s (%s):\nExpected: %s\nActual: %s", ErrChecksumMismatch, filename, expected.Algorithm, expected.Value, actual)
+		}
 	}
-	closed = true
 
 	if _, err := os.Stat(filename); err == nil {
 		if err := os.Remove(filename); err != nil {
-			return fmt.Errorf("failed to remove existing file: %w", err)
+			return resumed, fmt.Errorf("failed to remove existing file: %w", err)
 		}
 	}
 
 	if err := os.Rename(tempFile, filename); err != nil {
-		return fmt.Errorf("failed to rename temp file: %w", err)
+		return resumed, fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	if err := os.Remove(metaFile); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove download state: %v", err)
 	}
 
 	logger.Info("Download complete!")
 
-	return nil
+	return resumed, nil
 }
@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileChecksum(t *testing.T) {
+	body := []byte("server jar contents")
+	sum := sha256.Sum256(body)
+	good := hex.EncodeToString(sum[:])
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	oldClient := HTTPClient
+	HTTPClient = ts.Client()
+	defer func() { HTTPClient = oldClient }()
+
+	md5Sum := md5.Sum(body)
+
+	tests := []struct {
+		name     string
+		expected Checksum
+		wantErr  bool
+	}{
+		{"no checksum", Checksum{}, false},
+		{"matching checksum", Checksum{"sha256", good}, false},
+		{"matching checksum uppercase", Checksum{"sha256", strings.ToUpper(good) + "\n"}, false},
+		{"matching md5", Checksum{"md5", hex.EncodeToString(md5Sum[:])}, false},
+		{"mismatch", Checksum{"sha256", "0000000000000000000000000000000000000000000000000000000000000000"}, true},
+		{"md5 mismatch", Checksum{"md5", "00000000000000000000000000000000"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "server.jar")
+			err := DownloadFile(context.Background(), ts.URL, dest, tt.expected)
+			if tt.wantErr {
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+				}
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Error("mismatched download should not be installed")
+				}
+				if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+					t.Error("temp file should be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data, _ := os.ReadFile(dest); string(data) != string(body) {
+				t.Errorf("unexpected file contents %q", data)
+			}
+		})
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	body := []byte(strings.Repeat("0123456789abcdef", 4096))
+	sum := sha256.Sum256(body)
+	checksum := Checksum{"sha256", hex.EncodeToString(sum[:])}
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	half := len(body) / 2
+
+	corrupt := append([]byte(nil), body[:half]...)
+	corrupt[0] ^= 0xff
+
+	tests := []struct {
+		name      string
+		partial   []byte
+		meta      *partialDownload // nil leaves no state file; URL is filled in
+		wantRange bool
+	}{
+		{"resume with etag", body[:half], &partialDownload{ETag: `"v1"`}, true},
+		{"resume with last-modified", body[:half], &partialDownload{LastModified: modTime.Format(http.TimeFormat)}, true},
+		{"resource changed", body[:half], &partialDownload{ETag: `"v0"`}, true},
+		{"no download state", body[:half], nil, false},
+		{"corrupt partial file", corrupt, &partialDownload{ETag: `"v1"`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "server.jar", modTime, bytes.NewReader(body))
+			}))
+			defer ts.Close()
+			withClient(t, ts.Client())
+
+			dest := filepath.Join(t.TempDir(), "server.jar")
+			if err := os.WriteFile(dest+".part", tt.partial, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.meta != nil {
+				tt.meta.URL = ts.URL
+				if err := savePartialDownload(dest+".part.meta", *tt.meta); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := DownloadFile(context.Background(), ts.URL, dest, checksum); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data, _ := os.ReadFile(dest); !bytes.Equal(data, body) {
+				t.Errorf("downloaded file does not match (%d bytes)", len(data))
+			}
+			if got := ranges[0] != ""; got != tt.wantRange {
+				t.Errorf("range requested = %v, want %v (%q)", got, tt.wantRange, ranges)
+			}
+			for _, leftover := range []string{dest + ".part", dest + ".part.meta"} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s should be removed", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadFileInterrupted(t *testing.T) {
+	body := []byte(strings.Repeat("0123456789abcdef", 4096))
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		acceptRange bool
+		wantKept    bool
+	}{
+		{"resumable", true, true},
+		{"no range support", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				if len(ranges) > 1 {
+					w.Header().Set("ETag", `"v1"`)
+					http.ServeContent(w, r, "server.jar", modTime, bytes.NewReader(body))
+					return
+				}
+				if tt.acceptRange {
+					w.Header().Set("Accept-Ranges", "bytes")
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				// Drop the connection halfway through.
+				w.Write(body[:len(body)/2])
+			}))
+			defer ts.Close()
+			withClient(t, ts.Client())
+
+			dest := filepath.Join(t.TempDir(), "server.jar")
+			if err := DownloadFile(context.Background(), ts.URL, dest, Checksum{}); err == nil {
+				t.Fatal("expected an error for the interrupted download")
+			}
+			_, err := os.Stat(dest + ".part")
+			if kept := err == nil; kept != tt.wantKept {
+				t.Fatalf("partial file kept = %v, want %v", kept, tt.wantKept)
+			}
+
+			if err := DownloadFile(context.Background(), ts.URL, dest, Checksum{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data, _ := os.ReadFile(dest); !bytes.Equal(data, body) {
+				t.Errorf("downloaded file does not match (%d bytes)", len(data))
+			}
+			if resumed := ranges[1] != ""; resumed != tt.wantKept {
+				t.Errorf("second download resumed = %v, want %v", resumed, tt.wantKept)
+			}
+		})
+	}
+}
+
+func withClient(t *testing.T, c *http.Client) {
+	t.Helper()
+	old := HTTPClient
+	HTTPClient = c
+	t.Cleanup(func() { HTTPClient = old })
+}
+
+func TestDoRequestOffline(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer ts.Close()
+	withClient(t, ts.Client())
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	if _, err := DoRequest(context.Background(), ts.URL); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline, got %v", err)
+	}
+	dest := filepath.Join(t.TempDir(), "server.jar")
+	if err := DownloadFile(context.Background(), ts.URL, dest, Checksum{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests in offline mode, got %d", requests)
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const ServerPropertiesFile = "server.properties"
+
+// LoadServerProperties reads a Java properties file such as server.properties.
+// Only the subset of the format that Minecraft writes is supported.
+func LoadServerProperties(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			props[unescapeProperty(line)] = ""
+			continue
+		}
+		key := strings.TrimSpace(line[:sep])
+		value := strings.TrimSpace(line[sep+1:])
+		props[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return props, nil
+}
+
+func unescapeProperty(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		if escaped {
+			switch r {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -12,8 +12,8 @@ import (
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
 )
 
-func parseJarFileName(filename string) (version string, build int, ok bool) {
-	name := filename[6 : len(filename)-4]
+func parseJarFileName(prefix, filename string) (version string, build int, ok bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filename, prefix+"-"), ".jar")
 	lastDash := strings.LastIndex(name, "-")
 	if lastDash < 0 {
 		return "", 0, false
@@ -29,8 +29,10 @@ func parseJarFileName(filename string) (version string, build int, ok bool) {
 	return version, build, true
 }
 
-func FindJarFile() (string, error) {
-	files, err := filepath.Glob("paper-*.jar")
+// FindJarFile returns the newest "<prefix>-*.jar" in the working directory,
+// where prefix is the server type.
+func FindJarFile(prefix string) (string, error) {
+	files, err := filepath.Glob(prefix + "-*.jar")
 	if err != nil {
 		return "", fmt.Errorf("failed to search for JAR files: %w", err)
 	}
@@ -54,7 +56,7 @@ func FindJarFile() (string, error) {
 		}
 
 		baseName := filepath.Base(file)
-		_, build, _ := parseJarFileName(baseName)
+		_, build, _ := parseJarFileName(prefix, baseName)
 		jars = append(jars, jarInfo{
 			path:    file,
 			build:   build,
@@ -99,6 +101,27 @@ eula=true
 	return nil
 }
 
+// StripFormatting removes Minecraft § color and style codes from s.
+func StripFormatting(s string) string {
+	if !strings.ContainsRune(s, '§') {
+		return s
+	}
+	var sb strings.Builder
+	skip := false
+	for _, r := range s {
+		if skip {
+			skip = false
+			continue
+		}
+		if r == '§' {
+			skip = true
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
 func Pause() {
 	fmt.Print("\nPress Enter to exit...")
 	reader := bufio.NewReader(os.Stdin)
@@ -20,6 +20,6 @@ func BenchmarkFindJarFile(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		FindJarFile()
+		FindJarFile("paper")
 	}
 }
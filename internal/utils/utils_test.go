@@ -17,7 +17,7 @@ func TestFindJarFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	jar, err := FindJarFile()
+	jar, err := FindJarFile("paper")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -29,11 +29,56 @@ func TestFindJarFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	jar, err = FindJarFile()
+	jar, err = FindJarFile("paper")
 	if err != nil {
 		t.Fatal(err)
 	}
 	if jar != "paper-1.21.1-100.jar" {
 		t.Errorf("expected paper-1.21.1-100.jar, got %s", jar)
 	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "purpur-1.21.1-2300.jar"), []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if jar, _ = FindJarFile("paper"); jar != "paper-1.21.1-100.jar" {
+		t.Errorf("expected other server types to be ignored, got %s", jar)
+	}
+	if jar, _ = FindJarFile("purpur"); jar != "purpur-1.21.1-2300.jar" {
+		t.Errorf("expected purpur-1.21.1-2300.jar, got %s", jar)
+	}
+}
+
+func TestLoadServerProperties(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.properties")
+	content := "#Minecraft server properties\n" +
+		"enable-rcon=true\n" +
+		"rcon.password=p\\=ss\n" +
+		"motd=A Minecraft Server\n" +
+		"server-ip=\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := LoadServerProperties(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props["enable-rcon"] != "true" {
+		t.Errorf("expected enable-rcon=true, got %q", props["enable-rcon"])
+	}
+	if props["rcon.password"] != "p=ss" {
+		t.Errorf("expected escaped password, got %q", props["rcon.password"])
+	}
+	if props["motd"] != "A Minecraft Server" {
+		t.Errorf("unexpected motd: %q", props["motd"])
+	}
+	if v, ok := props["server-ip"]; !ok || v != "" {
+		t.Errorf("expected empty server-ip, got %q (present: %v)", v, ok)
+	}
+}
+
+func TestStripFormatting(t *testing.T) {
+	if got := StripFormatting("§6There are §c2§6 players online"); got != "There are 2 players online" {
+		t.Errorf("unexpected result: %q", got)
+	}
 }
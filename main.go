@@ -1,20 +1,21 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/backup"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/download"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/history"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
-	"github.com/nevcea-sub/minecraft-server-launcher/internal/server"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/update"
 	"github.com/nevcea-sub/minecraft-server-launcher/internal/utils"
 )
@@ -27,9 +28,15 @@ var (
 	workDir    = flag.String("w", "", "Override working directory")
 	version    = flag.String("v", "", "Override Minecraft version")
 	noPause    = flag.Bool("no-pause", false, "Don't pause on exit")
+
+	assumeYes      = flag.Bool("yes", false, "Answer yes to every prompt")
+	assumeNo       = flag.Bool("no", false, "Answer no to every prompt")
+	nonInteractive = flag.Bool("non-interactive", false, "Never prompt; use configured policies (implied when stdin is not a terminal)")
+	offline        = flag.Bool("offline", false, "Never access the network; use only local and cached JARs")
 )
 
 func main() {
+	flag.Usage = printUsage
 	flag.Parse()
 
 	level := logger.LevelInfo
@@ -42,24 +49,64 @@ func main() {
 	}
 	logger.SetLevel(level)
 
-	cfg, err := config.Load(*configFile)
+	if *assumeYes && *assumeNo {
+		fmt.Fprintln(os.Stderr, "-yes and -no cannot be used together")
+		os.Exit(2)
+	}
+
+	cmd, args, err := findCommand(flag.Args())
 	if err != nil {
-		logger.Fatal("Failed to load config: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		printUsage()
+		os.Exit(2)
+	}
+	// Pausing only makes sense when the launcher was started by double-clicking it.
+	if flag.NArg() > 0 || !interactive() {
+		*noPause = true
 	}
 
-	if cfg.LogFileEnable {
-		logPath := cfg.LogFile
-		if logPath == "" {
-			logPath = "launcher.log"
+	var cfg *config.Config
+	if !cmd.noConfig {
+		cfg, err = config.Load(*configFile)
+		if err != nil {
+			logger.Fatal("Failed to load config: %v", err)
 		}
-		if err := logger.SetLogFile(logPath); err != nil {
-			logger.Warn("Failed to open log file: %v", err)
-		} else {
-			defer logger.Close()
+		if *version != "" {
+			cfg.MinecraftVersion = *version
+		}
+		if *workDir != "" {
+			cfg.WorkDir = *workDir
+		}
+		if *offline {
+			cfg.Offline = true
+		}
+		utils.SetOffline(cfg.Offline)
+		if err := download.SetServerType(cfg.ServerType); err != nil {
+			logger.Fatal("Failed to load config: %v", err)
+		}
+		download.SetBuildPolicy(download.BuildPolicy{Channel: cfg.BuildChannel, Pinned: cfg.PinnedBuild})
+		update.SetChannel(cfg.LauncherUpdateChannel)
+		backup.SetFormat(cfg.BackupFormat)
+		jarCache, err := openCache(cfg)
+		if err != nil {
+			logger.Warn("Download cache disabled: %v", err)
+		}
+		download.SetCache(jarCache)
+
+		if cfg.LogFileEnable && os.Getenv(daemonEnv) == "" {
+			logPath := cfg.LogFile
+			if logPath == "" {
+				logPath = "launcher.log"
+			}
+			if err := logger.SetLogFile(logPath); err != nil {
+				logger.Warn("Failed to open log file: %v", err)
+			} else {
+				defer logger.Close()
+			}
 		}
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
 	defer func() {
@@ -69,7 +116,7 @@ func main() {
 		}
 	}()
 
-	if err := run(ctx, cfg); err != nil {
+	if err := cmd.run(ctx, cfg, args); err != nil && !errors.Is(err, flag.ErrHelp) {
 		if err == context.Canceled {
 			logger.Info("Operation cancelled by user")
 		} else {
@@ -90,82 +137,27 @@ func pauseAndExit(code int) {
 	os.Exit(code)
 }
 
-type javaCheckResult struct {
-	version    string
-	versionNum int
-	err        error
-}
-
-func run(ctx context.Context, cfg *config.Config) error {
-	logger.Info("Launcher started (version: %s)", update.GetCurrentVersion())
-
-	update.SetGitHubToken(cfg.GitHubToken)
-	checkLauncherUpdate(ctx)
-
-	if *version != "" {
-		cfg.MinecraftVersion = *version
-	}
-	if *workDir != "" {
-		cfg.WorkDir = *workDir
-	}
+func enterWorkDir(cfg *config.Config) error {
 	if cfg.WorkDir != "" && cfg.WorkDir != "." {
 		if err := os.Chdir(cfg.WorkDir); err != nil {
 			return fmt.Errorf("failed to change directory: %w", err)
 		}
 		logger.Info("Changed working directory to: %s", cfg.WorkDir)
 	}
+	return nil
+}
 
-	javaPath := cfg.JavaPath
-	if javaPath == "" {
-		javaPath = "java"
-	}
-	javaChan := make(chan javaCheckResult, 1)
-	go func() {
-		ver, num, err := server.CheckJava(javaPath)
-		javaChan <- javaCheckResult{ver, num, err}
-	}()
-
-	var avail int
-	if total, a, err := server.GetSystemRAM(); err != nil {
-		logger.Warn("Failed to get system RAM info: %v", err)
-	} else {
-		avail = a
-		logger.Info("System RAM: %d GB total, %d GB available", total, avail)
-	}
-
-	select {
-	case javaRes := <-javaChan:
-		if javaRes.err != nil {
-			return javaRes.err
-		}
-		logger.Info("Java version: %s", javaRes.version)
-
-		jarFile, err := prepareServerJar(ctx, cfg)
-		if err != nil {
-			return err
-		}
-
-		if cfg.AutoBackup {
-			if err := backup.PerformBackup(cfg.BackupWorlds, cfg.BackupDir, cfg.BackupCount); err != nil {
-				return fmt.Errorf("backup failed: %w", err)
-			}
-		}
-
-		maxRAM := server.CalculateSmartRAM(cfg.MaxRAM, cfg.AutoRAMPercentage, cfg.MinRAM, avail)
-		if cfg.MaxRAM == 0 {
-			logger.Info("Starting server with %dG - %dG RAM (auto: %d%% of available)", cfg.MinRAM, maxRAM, cfg.AutoRAMPercentage)
-		} else {
-			logger.Info("Starting server with %dG - %dG RAM", cfg.MinRAM, maxRAM)
-		}
-
-		return server.RunServer(ctx, jarFile, cfg.MinRAM, maxRAM, cfg.UseZGC, javaPath, javaRes.versionNum, cfg.ServerArgs)
+// launcherUpdatedEnv is set for a launcher started by a self-update, so a
+// release that still reports an older version cannot cause an update loop.
+const launcherUpdatedEnv = "PAPER_LAUNCHER_UPDATED"
 
-	case <-ctx.Done():
-		return ctx.Err()
+// checkLauncherUpdate reports a newer launcher release and, when install is
+// set, installs it and restarts the launcher with the same arguments.
+func checkLauncherUpdate(ctx context.Context, install bool) {
+	if utils.Offline() {
+		logger.Info("Offline mode: skipping launcher update check")
+		return
 	}
-}
-
-func checkLauncherUpdate(ctx context.Context) {
 	hasUpdate, release, err := update.CheckForUpdate(ctx)
 	if err != nil {
 		logger.Warn("Failed to check for launcher updates: %v", err)
@@ -178,21 +170,50 @@ func checkLauncherUpdate(ctx context.Context) {
 	if first := strings.SplitN(release.Body, "\n", 2)[0]; first != "" {
 		logger.Info("Release notes: %s", first)
 	}
-	logger.Info("Download: https://github.com/nevcea/minecraft-server-launcher/releases/latest")
+	if !install || os.Getenv(launcherUpdatedEnv) != "" {
+		link := release.HTMLURL
+		if link == "" {
+			link = "https://github.com/nevcea/minecraft-server-launcher/releases/latest"
+		}
+		logger.Info("Download: %s", link)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		logger.Warn("Failed to locate the launcher executable: %v", err)
+		return
+	}
+	if err := update.Install(ctx, release, exe); err != nil {
+		logger.Warn("Failed to update the launcher: %v", err)
+		return
+	}
+	logger.Info("Launcher updated to %s (previous version kept as %s), restarting...", release.TagName, filepath.Base(exe)+".old")
+	os.Setenv(launcherUpdatedEnv, release.TagName)
+	if err := reexec(exe); err != nil {
+		logger.Warn("Failed to restart the launcher, the update applies from the next start: %v", err)
+	}
 }
 
 func prepareServerJar(ctx context.Context, cfg *config.Config) (string, error) {
-	if err := utils.HandleEULA(); err != nil {
-		return "", err
+	if !download.IsProxy(cfg.ServerType) {
+		if err := utils.HandleEULA(); err != nil {
+			return "", err
+		}
 	}
 
-	jarFile, err := utils.FindJarFile()
+	jarFile, err := utils.FindJarFile(cfg.ServerType)
 	if err != nil {
 		return "", err
 	}
 
 	if jarFile == "" {
-		if !promptYesNo("No Paper JAR found. Download automatically?") {
+		action := decide(fmt.Sprintf("No %s JAR found. Download automatically?", cfg.ServerType), cfg.OnMissingJar,
+			config.PolicyDownload, config.PolicyAbort, config.PolicyDownload)
+		if action != config.PolicyDownload {
 			return "", fmt.Errorf("cannot start server without JAR file")
 		}
 		jarFile, err = download.DownloadJar(ctx, cfg.MinecraftVersion)
@@ -217,13 +238,18 @@ func validateAndUpdateJar(ctx context.Context, jarFile string, cfg *config.Confi
 	if expected != "" {
 		if err := utils.ValidateChecksum(jarFile, expected); err != nil {
 			logger.Warn("JAR checksum mismatch: %v", err)
-			if promptYesNo("Checksum validation failed. Re-download?") {
+			action := decide("Checksum validation failed. Re-download?", cfg.OnChecksumMismatch,
+				config.PolicyRedownload, config.PolicyIgnore, config.PolicyRedownload)
+			switch action {
+			case config.PolicyRedownload:
 				jarFile, err = download.DownloadJar(ctx, cfg.MinecraftVersion)
 				if err != nil {
 					return "", fmt.Errorf("failed to re-download JAR: %w", err)
 				}
 				logger.Info("Re-downloaded JAR: %s", jarFile)
-			} else {
+			case config.PolicyAbort:
+				return "", fmt.Errorf("refusing to start with a JAR that failed checksum validation")
+			default:
 				logger.Warn("Continuing with unverified JAR (not recommended)")
 			}
 		} else {
@@ -239,29 +265,47 @@ func validateAndUpdateJar(ctx context.Context, jarFile string, cfg *config.Confi
 		}
 	}
 
-	hasUpdate, newBuild, newJarName, err := download.CheckUpdate(ctx, jarFile)
+	if history.Held(cfg.VersionsDir) == filepath.Base(jarFile) {
+		logger.Info("%s was chosen with rollback, not checking for updates (run 'paper-launcher update' to resume)", jarFile)
+		return jarFile, nil
+	}
+
+	if utils.Offline() {
+		logger.Info("Offline mode: skipping server update check for %s", jarFile)
+		return jarFile, nil
+	}
+
+	upd, err := download.CheckUpdate(ctx, jarFile)
 	if err != nil {
 		logger.Warn("Failed to check for server updates: %v", err)
 		return jarFile, nil
 	}
-	if !hasUpdate {
+	if !upd.Available {
+		if upd.SkipReason != "" {
+			logger.Info("Not updating server JAR: %s", upd.SkipReason)
+		}
 		return jarFile, nil
 	}
 
-	logger.Info("Update available: %s (build %d)", newJarName, newBuild)
-	if !cfg.AutoUpdate && !promptYesNo("Update server JAR?") {
+	logUpdate(upd)
+	action := decide("Update server JAR?", cfg.OnUpdate, config.PolicyUpdate, config.PolicySkip, config.PolicySkip)
+	if action != config.PolicyUpdate {
+		logger.Info("Skipping server JAR update")
 		return jarFile, nil
 	}
 
-	oldBackup := jarFile + ".old"
-	if err := os.Rename(jarFile, oldBackup); err == nil {
-		logger.Info("Backed up old JAR to %s", oldBackup)
+	archived := true
+	if err := history.Archive(cfg.VersionsDir, jarFile); err != nil {
+		logger.Warn("Failed to keep the current JAR for rollback: %v", err)
+		archived = false
 	}
 
 	newJar, err := download.DownloadJar(ctx, cfg.MinecraftVersion)
 	if err != nil {
-		if _, statErr := os.Stat(oldBackup); statErr == nil {
-			if os.Rename(oldBackup, jarFile) == nil {
+		if archived {
+			if err := history.Unarchive(cfg.VersionsDir, filepath.Base(jarFile), filepath.Dir(jarFile)); err != nil {
+				logger.Warn("Failed to restore the original JAR: %v", err)
+			} else {
 				logger.Info("Restored original JAR")
 			}
 		}
@@ -269,22 +313,30 @@ func validateAndUpdateJar(ctx context.Context, jarFile string, cfg *config.Confi
 	}
 
 	logger.Info("Updated to: %s", newJar)
+	if archived {
+		logger.Info("Previous JAR kept in %s ('paper-launcher rollback' to switch back)", cfg.VersionsDir)
+		pruneHistory(cfg)
+	}
 	return newJar, nil
 }
 
-func promptYesNo(message string) bool {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Printf("[PROMPT] %s [Y/N]: ", message)
-		response, err := reader.ReadString('\n')
-		if err != nil {
-			return false
-		}
-		switch strings.TrimSpace(strings.ToLower(response)) {
-		case "y", "yes":
-			return true
-		case "n", "no":
-			return false
+const maxChangesShown = 10
+
+// logUpdate describes an available server update and the commits it contains.
+func logUpdate(upd *download.Update) {
+	logger.Info("Update available: %s (%s channel)", upd.Latest.Download.Name, strings.ToLower(upd.Latest.Channel))
+	if upd.SkipReason != "" {
+		logger.Info("Not the newest build: %s", upd.SkipReason)
+	}
+	shown := 0
+	for _, b := range upd.Changes {
+		for _, c := range b.Commits {
+			if shown == maxChangesShown {
+				logger.Info("  ...")
+				return
+			}
+			logger.Info("  #%s %s", b.ID, strings.SplitN(c.Message, "\n", 2)[0])
+			shown++
 		}
 	}
 }
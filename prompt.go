@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/config"
+	"github.com/nevcea-sub/minecraft-server-launcher/internal/logger"
+)
+
+// interactive reports whether the launcher may ask questions on stdin.
+func interactive() bool {
+	if *nonInteractive {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// decide resolves a configured policy to an action. Only the "prompt" policy
+// asks the user; -yes/-no pick yesAction/noAction, and without a terminal
+// the unattended action is used.
+func decide(question, policy, yesAction, noAction, unattended string) string {
+	if policy != "" && policy != config.PolicyPrompt {
+		return policy
+	}
+	switch {
+	case *assumeYes:
+		logger.Info("%s -> %s (-yes)", question, yesAction)
+		return yesAction
+	case *assumeNo:
+		logger.Info("%s -> %s (-no)", question, noAction)
+		return noAction
+	case !interactive():
+		logger.Info("%s -> %s (non-interactive)", question, unattended)
+		return unattended
+	}
+
+	answer, err := promptYesNo(question)
+	if err != nil {
+		logger.Warn("Failed to read answer (%v), using %s", err, unattended)
+		return unattended
+	}
+	if answer {
+		return yesAction
+	}
+	return noAction
+}
+
+func promptYesNo(message string) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("[PROMPT] %s [Y/N]: ", message)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+		switch strings.TrimSpace(strings.ToLower(response)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
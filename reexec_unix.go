@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reexec replaces the launcher process with the binary at exe, keeping the
+// PID so service managers and detach keep tracking it.
+func reexec(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
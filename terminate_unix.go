@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// terminate asks the launcher with the given PID to shut down. SIGTERM
+// cancels its context, which stops the server through the console.
+func terminate(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal launcher (pid %d): %w", pid, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "fmt"
+
+// terminate is not available on Windows, where console processes cannot be
+// sent a graceful shutdown signal from another console.
+func terminate(pid int) error {
+	return fmt.Errorf("stopping a launcher (pid %d) from another console is not supported on Windows; type %q in its console instead", pid, "stop")
+}